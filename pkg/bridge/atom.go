@@ -16,19 +16,28 @@ import (
 type Atom struct {
 	ctx *Context
 	ref C.JSAtom
+	// the [Scope] that will free this atom upon exiting, or `nil` if the atom does not belong to any scope.
+	scope *Scope
 }
 
 // free up an [Atom].
 func (atom *Atom) Free() {
-	C.JS_FreeAtom(atom.ctx.ref, atom.ref)
+	atom.ctx.rt.assertOwner()
+	if LeakDetection {
+		atom.ctx.leaks.releaseAtom(atom, "freed")
+	}
+	atom.scope = nil
+	C.JS_FreeAtom(atom.cctx(), atom.ref)
 }
 
 // decrements the reference count of a quickjs [Atom] object _when_ its [Context] exits/frees up (i.e. when [Context.Free] is called).
 //
 // this is opposed to freeing up the value _immediately_ via [Atom.Free].
 // it is intended for cached value properties, such as `length` and `size`.
+//
+// under the hood, the atom is moved over to the context's own [Scope], which exits when the context is freed.
 func (atom *Atom) FreeOnExit() {
-	atom.ctx.atomFreeupList = append(atom.ctx.atomFreeupList, atom)
+	atom.ctx.exitScope.addAtom(atom)
 }
 
 // increments the reference count of a quickjs [Atom] object, and duplicates its [Atom] wrapper.
@@ -42,7 +51,7 @@ func (atom *Atom) Dupe() *Atom {
 	if atom == nil || atom.ctx == nil {
 		return nil
 	}
-	return &Atom{ctx: atom.ctx, ref: C.JS_DupAtom(atom.ctx.ref, atom.ref)}
+	return atom.ctx.newAtom(C.JS_DupAtom(atom.cctx(), atom.ref))
 }
 
 // create a new quickjs atom from a given go-string.
@@ -52,7 +61,7 @@ func (atom *Atom) Dupe() *Atom {
 func (ctx *Context) NewAtom(v string) *Atom {
 	cstr_ptr := C.CString(v)
 	defer C.free(unsafe.Pointer(cstr_ptr))
-	return &Atom{ctx: ctx, ref: C.JS_NewAtom(ctx.ref, cstr_ptr)}
+	return ctx.newAtom(C.JS_NewAtom(ctx.ref, cstr_ptr))
 }
 
 // create a new quickjs atom from a given numeric property index.
@@ -60,7 +69,7 @@ func (ctx *Context) NewAtom(v string) *Atom {
 //
 // @should-free
 func (ctx *Context) NewAtomIdx(idx uint32) *Atom {
-	return &Atom{ctx: ctx, ref: C.JS_NewAtomUInt32(ctx.ref, C.uint32_t(idx))}
+	return ctx.newAtom(C.JS_NewAtomUInt32(ctx.ref, C.uint32_t(idx)))
 }
 
 // returns the string representation of the atomic property.
@@ -75,7 +84,7 @@ func (atom *Atom) ToString() string {
 //
 // @should-free
 func (atom *Atom) ToValue() *Value {
-	return &Value{ctx: atom.ctx, ref: C.JS_AtomToValue(atom.ctx.ref, atom.ref)}
+	return atom.ctx.newValue(C.JS_AtomToValue(atom.cctx(), atom.ref))
 }
 
 // converts the primitive javascript [Value] property key to its of the [Atom]ic representation.
@@ -83,11 +92,11 @@ func (atom *Atom) ToValue() *Value {
 //
 // @should-free
 func (val *Value) ToAtom() *Atom {
-	atom_ref := C.JS_ValueToAtom(val.ctx.ref, val.ref)
+	atom_ref := C.JS_ValueToAtom(val.cctx(), val.ref)
 	if atom_ref == C.JS_ATOM_NULL {
 		panic("[Value.ToAtom]: failed to convert the provided value to an atom, possibly because the value is not a valid property key (i.e. neither a number, nor a string, nor a symbol).")
 	}
-	return &Atom{ctx: val.ctx, ref: atom_ref}
+	return val.ctx.newAtom(atom_ref)
 }
 
 // set a javascript `Object`'s atomic property `prop_atom` to a certain value `val`.
@@ -98,7 +107,7 @@ func (val *Value) ToAtom() *Atom {
 //
 // @ownership-transfer
 func (obj *Value) SetAtom(prop_atom *Atom, val *Value) {
-	success := C.JS_SetProperty(obj.ctx.ref, obj.ref, prop_atom.ref, val.ref)
+	success := C.JS_SetProperty(obj.cctx(), obj.ref, prop_atom.ref, val.transfer())
 	// success is either `-1` (exception), `0` (false), or `1` (true).
 	if success < 0 {
 		panic(fmt.Sprintf(`[Object.SetAtom]: setting the value of the atomic property "%s" resulted in an exception. your value may not be an "Object".`, prop_atom.ToString()))
@@ -113,7 +122,7 @@ func (obj *Value) SetAtom(prop_atom *Atom, val *Value) {
 //
 // @should-free
 func (obj *Value) GetAtom(prop_atom *Atom) *Value {
-	return &Value{ctx: obj.ctx, ref: C.JS_GetProperty(obj.ctx.ref, obj.ref, prop_atom.ref)}
+	return obj.ctx.newValue(C.JS_GetProperty(obj.cctx(), obj.ref, prop_atom.ref))
 }
 
 // dictates whether or not an `Object` has a certain atomic property `prop_atom`.
@@ -122,7 +131,7 @@ func (obj *Value) GetAtom(prop_atom *Atom) *Value {
 // but, **you**, the user, will have to bear the overhead of creating the `prop_atom`,
 // and also bear the burden of freeing it once you've made all the necessary changes related to this atomic property.
 func (obj *Value) HasAtom(prop_atom *Atom) bool {
-	success := C.JS_HasProperty(obj.ctx.ref, obj.ref, prop_atom.ref)
+	success := C.JS_HasProperty(obj.cctx(), obj.ref, prop_atom.ref)
 	if success >= 0 {
 		return (success == 1)
 	}
@@ -138,7 +147,7 @@ func (obj *Value) HasAtom(prop_atom *Atom) bool {
 // but, **you**, the user, will have to bear the overhead of creating the `prop_atom`,
 // and also bear the burden of freeing it once you've made all the necessary changes related to this atomic property.
 func (obj *Value) DeleteAtom(prop_atom *Atom) bool {
-	success := C.JS_DeleteProperty(obj.ctx.ref, obj.ref, prop_atom.ref, 1)
+	success := C.JS_DeleteProperty(obj.cctx(), obj.ref, prop_atom.ref, 1)
 	if success >= 0 {
 		return (success == 1)
 	}
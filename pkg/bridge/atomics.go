@@ -0,0 +1,281 @@
+// this file contains our implementation of `Atomics.wait` and `Atomics.notify`, which replaces quickjs's own implementation in every context,
+// along with their go counterparts ([SharedBuffer.Wait] and [SharedBuffer.Notify]).
+//
+// the waiters of all runtimes (and goroutines) are kept in a single process-wide registry, keyed by the address of the shared memory they wait on.
+// since the memory of a `SharedArrayBuffer` is shared rather than copied across runtimes (see `./shared.go`),
+// a `notify` in one runtime wakes up the `wait`s of all other runtimes and goroutines on the same location.
+//
+// unlike quickjs's implementation, a javascript `Atomics.wait` can be interrupted (see `./interrupt.go`),
+// which is what allows a worker's `terminate()` and cancelled go contexts to stop a worker that is blocked forever.
+// note that `Atomics.wait` is permitted on any runtime, including the main one, so it is up to you not to block the event loop of a runtime
+// that is supposed to wake the waiter up.
+
+package bridge
+
+/*
+#include "./include0_quickjs.h"
+
+// throw the javascript errors that the spec demands from `Atomics.wait` and `Atomics.notify` (cgo cannot call variadic functions).
+static inline JSValue throwAtomicsTypeError(JSContext *ctx, const char *message) { return JS_ThrowTypeError(ctx, "%s", message); }
+static inline JSValue throwAtomicsRangeError(JSContext *ctx, const char *message) { return JS_ThrowRangeError(ctx, "%s", message); }
+*/
+import "C"
+import (
+	context "context"
+	fmt "fmt"
+	math "math"
+	sync "sync"
+	sync_atomic "sync/atomic"
+	time "time"
+	unsafe "unsafe"
+)
+
+// the result of a wait on shared memory, with the same strings as those returned by javascript's `Atomics.wait`.
+type WaitResult string
+
+const (
+	WaitOk       WaitResult = "ok"        // the waiter was woken up by a notification.
+	WaitNotEqual WaitResult = "not-equal" // the memory did not hold the expected value, so the waiter did not go to sleep.
+	WaitTimedOut WaitResult = "timed-out" // the timeout expired (or the go context ended) before a notification arrived.
+)
+
+// a single sleeping waiter, which is woken up by closing its channel.
+type atomicsWaiter struct {
+	notified chan struct{}
+}
+
+// the registry of all sleeping waiters of the process, keyed by the addresses that they wait on, in the order that they went to sleep.
+var atomicsWaiters = struct {
+	mutex  sync.Mutex
+	queues map[uintptr][]*atomicsWaiter
+}{queues: map[uintptr][]*atomicsWaiter{}}
+
+// sleep on the shared memory at `addr` if `matches` reports that it holds the expected value, until either a notification arrives,
+// the `timeout` channel fires, or the `cancel` channel is closed (a `nil` channel never fires).
+// the value is compared while the registry is locked, so a notification that follows a store can never slip in between.
+func atomicsWait(addr uintptr, matches func() bool, timeout <-chan time.Time, cancel <-chan struct{}) WaitResult {
+	registry := &atomicsWaiters
+	registry.mutex.Lock()
+	if !matches() {
+		registry.mutex.Unlock()
+		return WaitNotEqual
+	}
+	waiter := &atomicsWaiter{notified: make(chan struct{})}
+	registry.queues[addr] = append(registry.queues[addr], waiter)
+	registry.mutex.Unlock()
+
+	select {
+	case <-waiter.notified:
+		return WaitOk
+	case <-timeout:
+	case <-cancel:
+	}
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	queue := registry.queues[addr]
+	for i, queued := range queue {
+		if queued == waiter {
+			queue = append(queue[:i], queue[i+1:]...)
+			if len(queue) == 0 {
+				delete(registry.queues, addr)
+			} else {
+				registry.queues[addr] = queue
+			}
+			return WaitTimedOut
+		}
+	}
+	// the waiter was dequeued by a notification that raced with the timeout, so it counts as notified.
+	return WaitOk
+}
+
+// wake up to `count` of the waiters sleeping on the shared memory at `addr` (or all of them if `count` is negative), oldest first.
+// returns the number of waiters that were woken up.
+func atomicsNotify(addr uintptr, count int) int {
+	registry := &atomicsWaiters
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	queue := registry.queues[addr]
+	if count < 0 || count > len(queue) {
+		count = len(queue)
+	}
+	for _, waiter := range queue[:count] {
+		close(waiter.notified)
+	}
+	if count == len(queue) {
+		delete(registry.queues, addr)
+	} else {
+		registry.queues[addr] = queue[count:]
+	}
+	return count
+}
+
+// get a pointer to the `index`th 32-bit integer of the shared memory, for use with the functions of the `sync/atomic` package.
+// the indexing is the same as that of an `Int32Array` spanning the whole buffer, and an out of range `index` panics.
+func (buf *SharedBuffer) Int32(index int) *int32 {
+	if index < 0 || index >= buf.size/4 {
+		panic(fmt.Sprintf(`[SharedBuffer.Int32]: index "%d" is out of range for a buffer of "%d" bytes.`, index, buf.size))
+	}
+	return (*int32)(unsafe.Add(buf.ptr, index*4))
+}
+
+// the go counterpart of javascript's `Atomics.wait`: if the `index`th 32-bit integer of the shared memory (see [SharedBuffer.Int32])
+// holds the `expected` value, then block until a notification for it arrives (from either javascript's `Atomics.notify`, or [SharedBuffer.Notify]).
+//
+// the wait ends with [WaitTimedOut] and the context's error once `goctx` is cancelled or its deadline is exceeded.
+func (buf *SharedBuffer) Wait(goctx context.Context, index int, expected int32) (WaitResult, error) {
+	ptr := buf.Int32(index)
+	result := atomicsWait(uintptr(unsafe.Pointer(ptr)), func() bool {
+		return sync_atomic.LoadInt32(ptr) == expected
+	}, nil, goctx.Done())
+	if result == WaitTimedOut {
+		return result, goctx.Err()
+	}
+	return result, nil
+}
+
+// the go counterpart of javascript's `Atomics.notify`: wake up to `count` of the waiters (or all of them if `count` is negative)
+// that sleep on the `index`th 32-bit integer of the shared memory, in any runtime or goroutine.
+// returns the number of waiters that were woken up.
+func (buf *SharedBuffer) Notify(index int, count int) int {
+	return atomicsNotify(uintptr(unsafe.Pointer(buf.Int32(index))), count)
+}
+
+// replace the `wait` and `notify` methods of the context's `Atomics` object with ours.
+func (ctx *Context) injectAtomics() {
+	atomics := ctx.valueCache.globalThis.Get("Atomics")
+	defer atomics.Free()
+	if !atomics.IsObject() {
+		return
+	}
+	flags := C.int(C.JS_PROP_CONFIGURABLE | C.JS_PROP_WRITABLE)
+	atomics.define("wait", ctx.NewFunction("wait", 4, jsAtomicsWait), flags)
+	atomics.define("notify", ctx.NewFunction("notify", 3, jsAtomicsNotify), flags)
+}
+
+// a validated element of an `Int32Array` or a `BigInt64Array`, which is the target of `Atomics.wait` and `Atomics.notify`.
+type atomicsTarget struct {
+	ptr    unsafe.Pointer
+	kind   TypedArrayEnum
+	shared bool
+}
+
+// javascript's `ToIntegerOrInfinity` for an already converted number, which truncates it towards zero, and maps `NaN` to zero.
+func toIntegerOrInfinity(num float64) float64 {
+	if math.IsNaN(num) {
+		return 0
+	}
+	return math.Trunc(num)
+}
+
+// throw a javascript `TypeError` from `Atomics.wait` or `Atomics.notify`, and return the exception that the [GoFunction] must return.
+func (ctx *Context) throwAtomicsTypeError(message string) *Value {
+	c_message := C.CString(message)
+	defer C.free(unsafe.Pointer(c_message))
+	return &Value{ctx: ctx, ref: C.throwAtomicsTypeError(ctx.ref, c_message)}
+}
+
+// same as [Context.throwAtomicsTypeError], but for a `RangeError`.
+func (ctx *Context) throwAtomicsRangeError(message string) *Value {
+	c_message := C.CString(message)
+	defer C.free(unsafe.Pointer(c_message))
+	return &Value{ctx: ctx, ref: C.throwAtomicsRangeError(ctx.ref, c_message)}
+}
+
+// validate the `typed_array` and `index` arguments of `Atomics.wait` and `Atomics.notify`, and locate the addressed element.
+// a `TypeError` is thrown for anything other than an `Int32Array` or a `BigInt64Array` (or for a non-shared one when `waitable` is set),
+// and a `RangeError` is thrown for an out of range index. the thrown exception is returned in place of the target, and it must be returned by the [GoFunction].
+func atomicsLocate(typed_array *Value, index *Value, waitable bool) (atomicsTarget, *Value) {
+	ctx := typed_array.ctx
+	kind := TypedArrayInvalid
+	if typed_array.IsTypedArray(TypedArrayAny) {
+		kind = typed_array.IdentifyTypedArray()
+	}
+	if kind != TypedArrayInt32 && kind != TypedArrayBigInt64 {
+		return atomicsTarget{}, ctx.throwAtomicsTypeError("[Atomics]: expected an Int32Array or a BigInt64Array")
+	}
+	info := typed_array.IdentifyTypedArrayInfo()
+	defer info.Buffer.Free()
+	shared := info.Buffer.IsSharedArrayBuffer()
+	if waitable && !shared {
+		return atomicsTarget{}, ctx.throwAtomicsTypeError("[Atomics.wait]: the typed array must be backed by a SharedArrayBuffer")
+	}
+	// the index goes through javascript's `ToIndex`, so it is truncated, and an `undefined` (or `NaN`) index becomes `0`.
+	var c_index C.double
+	if C.JS_ToFloat64(ctx.ref, &c_index, index.ref) < 0 {
+		return atomicsTarget{}, ctx.NewException()
+	}
+	idx := toIntegerOrInfinity(float64(c_index))
+	length := info.ByteLength / info.BytesPerElement
+	if idx < 0 || idx >= float64(length) {
+		return atomicsTarget{}, ctx.throwAtomicsRangeError(fmt.Sprintf(`[Atomics]: index "%v" is out of range`, idx))
+	}
+	var c_size C.size_t
+	base := unsafe.Pointer(C.JS_GetArrayBuffer(ctx.ref, &c_size, info.Buffer.ref))
+	return atomicsTarget{
+		ptr:    unsafe.Add(base, info.ByteOffset+uint(idx)*info.BytesPerElement),
+		kind:   kind,
+		shared: shared,
+	}, nil
+}
+
+// our `Atomics.wait(typed_array, index, value, timeout_ms)`, which blocks the runtime until it is notified, interrupted, or timed out.
+func jsAtomicsWait(ctx *Context, this *Value, args []*Value) (*Value, error) {
+	target, exception := atomicsLocate(args[0], args[1], true)
+	if exception != nil {
+		return exception, nil
+	}
+	// the expected value goes through javascript's `ToBigInt64` or `ToInt32`, either of which may throw (such as for a number in a `BigInt64Array`).
+	var matches func() bool
+	if target.kind == TypedArrayBigInt64 {
+		var c_expected C.int64_t
+		if C.JS_ToBigInt64(ctx.ref, &c_expected, args[2].ref) < 0 {
+			return ctx.NewException(), nil
+		}
+		expected, ptr := int64(c_expected), (*int64)(target.ptr)
+		matches = func() bool { return sync_atomic.LoadInt64(ptr) == expected }
+	} else {
+		var c_expected C.int32_t
+		if C.JS_ToInt32(ctx.ref, &c_expected, args[2].ref) < 0 {
+			return ctx.NewException(), nil
+		}
+		expected, ptr := int32(c_expected), (*int32)(target.ptr)
+		matches = func() bool { return sync_atomic.LoadInt32(ptr) == expected }
+	}
+	// an `undefined` (or `NaN`) timeout waits forever, while a negative one does not wait at all.
+	var timeout <-chan time.Time
+	if timeout_ms := args[3].ToFloat64(); !args[3].IsUndefined() && !math.IsNaN(timeout_ms) && !math.IsInf(timeout_ms, 1) {
+		timer := time.NewTimer(time.Duration(max(timeout_ms, 0) * float64(time.Millisecond)))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	interrupted, stop := ctx.rt.interrupt.watch()
+	defer stop()
+	result := atomicsWait(uintptr(target.ptr), matches, timeout, interrupted)
+	if result == WaitTimedOut {
+		select {
+		case <-interrupted:
+			return ctx.throwInterrupted(), nil
+		default:
+		}
+	}
+	return ctx.NewString(string(result)), nil
+}
+
+// our `Atomics.notify(typed_array, index, count)`, which wakes up to `count` waiters (or all of them if it is `undefined`).
+func jsAtomicsNotify(ctx *Context, this *Value, args []*Value) (*Value, error) {
+	target, exception := atomicsLocate(args[0], args[1], false)
+	if exception != nil {
+		return exception, nil
+	}
+	// an `undefined` count wakes up everyone, while any other count goes through javascript's `ToIntegerOrInfinity`,
+	// so a `NaN` (or negative) count wakes up nobody.
+	count := -1
+	if count_float := toIntegerOrInfinity(args[2].ToFloat64()); !args[2].IsUndefined() && count_float < math.MaxInt32 {
+		count = int(max(count_float, 0))
+	}
+	// nobody can wait on a non-shared buffer, so there is nobody to wake up either.
+	if !target.shared {
+		return ctx.NewInt32(0), nil
+	}
+	return ctx.NewInt64(int64(atomicsNotify(uintptr(target.ptr), count))), nil
+}
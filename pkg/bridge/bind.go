@@ -0,0 +1,190 @@
+// this file contains the reflection-based automatic binding of go-structs to javascript objects,
+// where the exported fields of a struct become accessor properties, and its exported methods become javascript functions.
+//
+// the values of fields, arguments, and return values are converted using the same rules as [Context.Marshal] and [Value.Unmarshal]
+// (see the table at the top of `./marshal.go`), with the following exceptions:
+//   - a `*bridge.Value` argument is passed _borrowed_ (i.e. not duplicated), so you must not free it.
+//   - a trailing `error` return value is _thrown_ as a javascript `Error` when it isn't `nil`.
+
+package bridge
+
+/*
+#include "./include0_quickjs.h"
+*/
+import "C"
+import (
+	fmt "fmt"
+	reflect "reflect"
+	strings "strings"
+	unicode "unicode"
+)
+
+var (
+	reflectValueType = reflect.TypeFor[*Value]()
+	reflectErrorType = reflect.TypeFor[error]()
+)
+
+// expose a go-struct to javascript as the global object `globalThis[name]`.
+//
+// `v` must be a pointer to a struct, so that changes made from either side are visible to the other side.
+// the binding is done via reflection, with the following rules:
+//   - each exported field becomes an enumerable accessor property (getter and setter),
+//     which reads from and writes to the struct's field directly.
+//   - each exported method (including pointer-receiver methods) becomes a javascript method.
+//     if the method's last return value is an `error`, then a non-`nil` error will be thrown in javascript.
+//   - exported go-names are converted to camelCase (for instance, `ReadFile` becomes `readFile`, and `ID` becomes `id`).
+//   - the `js:"name"` struct tag renames a field, while the `js:"-"` tag hides it from javascript.
+//
+// see the comment at the top of this file for the supported types of fields, arguments, and return values.
+func (ctx *Context) BindStruct(name string, v any) error {
+	obj, err := ctx.newStructBinding(v)
+	if err != nil {
+		return err
+	}
+	ctx.GetGlobalThis().Set(name, obj)
+	return nil
+}
+
+// create a javascript object that is bound to the go-struct pointer `v` (see [Context.BindStruct]).
+//
+// @should-free
+func (ctx *Context) newStructBinding(v any) (*Value, error) {
+	ptr_val := reflect.ValueOf(v)
+	if ptr_val.Kind() != reflect.Pointer || ptr_val.IsNil() || ptr_val.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("[Context.BindStruct]: expected a non-nil pointer to a struct, got: %T", v)
+	}
+	struct_val := ptr_val.Elem()
+	obj := ctx.NewObject()
+	for _, field := range reflect.VisibleFields(struct_val.Type()) {
+		// embedded structs are skipped, since their exported fields get promoted and visited individually anyway.
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		js_name, _, hidden := parseStructTag(field)
+		if hidden {
+			continue
+		}
+		field_index := field.Index
+		getter := ctx.NewFunction("get "+js_name, 0, func(ctx *Context, this *Value, args []*Value) (*Value, error) {
+			field_val, err := struct_val.FieldByIndexErr(field_index)
+			if err != nil {
+				return nil, err
+			}
+			return ctx.marshalReflect(field_val, "")
+		})
+		setter := ctx.NewFunction("set "+js_name, 1, func(ctx *Context, this *Value, args []*Value) (*Value, error) {
+			field_val, err := struct_val.FieldByIndexErr(field_index)
+			if err != nil {
+				return nil, err
+			}
+			if err := args[0].unmarshalReflect(field_val, ""); err != nil {
+				return nil, fmt.Errorf(`cannot set the field "%s": %w`, js_name, err)
+			}
+			return nil, nil
+		})
+		obj.defineGetSet(js_name, getter, setter, C.JS_PROP_CONFIGURABLE|C.JS_PROP_ENUMERABLE)
+	}
+	ptr_type := ptr_val.Type()
+	for i := range ptr_type.NumMethod() {
+		js_name := jsIdentifier(ptr_type.Method(i).Name)
+		method := ctx.newReflectFunction(js_name, ptr_val.Method(i))
+		obj.define(js_name, method, C.JS_PROP_CONFIGURABLE|C.JS_PROP_WRITABLE)
+	}
+	return obj, nil
+}
+
+// parse the `js` struct tag of a field, returning its javascript property name, its comma separated options, and whether it should be hidden.
+func parseStructTag(field reflect.StructField) (js_name string, options string, hidden bool) {
+	tag := field.Tag.Get("js")
+	if tag == "-" {
+		return "", "", true
+	}
+	js_name, options, _ = strings.Cut(tag, ",")
+	if js_name == "" {
+		js_name = jsIdentifier(field.Name)
+	}
+	return js_name, options, false
+}
+
+// converts an exported go identifier to the conventional javascript camelCase, by lower-casing its leading run of capital letters.
+// for example: `"Name"` becomes `"name"`, `"ID"` becomes `"id"`, and `"HTTPServer"` becomes `"httpServer"`.
+func jsIdentifier(go_name string) string {
+	runes := []rune(go_name)
+	upper_len := 0
+	for upper_len < len(runes) && unicode.IsUpper(runes[upper_len]) {
+		upper_len++
+	}
+	// the last capital letter of an acronym that is followed by another word belongs to that word (i.e. the "S" in "HTTPServer").
+	if upper_len > 1 && upper_len < len(runes) {
+		upper_len--
+	}
+	for i := range upper_len {
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
+
+// create a javascript function that calls the reflected go-function `fn`, converting its arguments and results automatically.
+//
+// @should-free
+func (ctx *Context) newReflectFunction(name string, fn reflect.Value) *Value {
+	fn_type := fn.Type()
+	fixed_args_len := fn_type.NumIn()
+	if fn_type.IsVariadic() {
+		fixed_args_len--
+	}
+	return ctx.NewFunction(name, fixed_args_len, func(ctx *Context, this *Value, args []*Value) (*Value, error) {
+		in := make([]reflect.Value, 0, len(args))
+		for i, arg := range args {
+			var arg_type reflect.Type
+			if i < fixed_args_len {
+				arg_type = fn_type.In(i)
+			} else if fn_type.IsVariadic() {
+				arg_type = fn_type.In(fixed_args_len).Elem()
+			} else {
+				// any extra arguments that the go-function does not accept are simply ignored, just like in javascript.
+				break
+			}
+			if arg_type == reflectValueType {
+				// javascript values are passed as is (i.e. borrowed), so the go-function must dupe them if it wishes to keep them.
+				in = append(in, reflect.ValueOf(arg))
+				continue
+			}
+			arg_val := reflect.New(arg_type).Elem()
+			if err := arg.unmarshalReflect(arg_val, ""); err != nil {
+				return nil, fmt.Errorf("argument %d of %s(): %w", i, name, err)
+			}
+			in = append(in, arg_val)
+		}
+		return ctx.reflectResults(fn.Call(in))
+	})
+}
+
+// convert the results of a reflected go-function call to a single javascript value.
+// a trailing `error` result is returned as the go `error`, while multiple non-error results are packed into a javascript `Array`.
+//
+// @should-free
+func (ctx *Context) reflectResults(out []reflect.Value) (*Value, error) {
+	if n := len(out); n > 0 && out[n-1].Type() == reflectErrorType {
+		if err, _ := out[n-1].Interface().(error); err != nil {
+			return nil, err
+		}
+		out = out[:n-1]
+	}
+	switch len(out) {
+	case 0:
+		return nil, nil
+	case 1:
+		return ctx.marshalReflect(out[0], "")
+	}
+	js_arr := ctx.NewArray()
+	for i, result := range out {
+		js_result, err := ctx.marshalReflect(result, "")
+		if err != nil {
+			js_arr.Free()
+			return nil, err
+		}
+		js_arr.SetIdx(int64(i), js_result)
+	}
+	return js_arr, nil
+}
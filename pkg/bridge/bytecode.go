@@ -0,0 +1,147 @@
+// this file contains the compilation of javascript source code to quickjs bytecode, and the evaluation of such bytecode,
+// so that large scripts need not be parsed again on every startup of your native binary.
+//
+// quickjs bytecode is only compatible with the exact quickjs version that produced it,
+// thus our bytecode is prefixed with a small header carrying that version, so that incompatible bytecode is rejected rather than crashing:
+//
+// | bytes       | content                                                  |
+// |-------------|----------------------------------------------------------|
+// | `4`         | the magic string `"qjbc"`                                |
+// | `1`         | the length `n` of the version string                     |
+// | `n`         | the quickjs version string (see [QuickjsVersion])        |
+// | `1`         | the kind of the compiled code (`0` script, `1` module)   |
+// | _remaining_ | the bytecode, as written by quickjs's `JS_WriteObject`   |
+
+package bridge
+
+/*
+#include "./include1_helpers.h"
+#include "./include2_version.h"
+*/
+import "C"
+import (
+	bytes "bytes"
+	errors "errors"
+	fmt "fmt"
+	unsafe "unsafe"
+)
+
+// the version of the quickjs library that this package binds to.
+// it is read from the very `CONFIG_VERSION` macro that quickjs is compiled with (see `./include2_version.h`).
+const QuickjsVersion = C.CONFIG_VERSION
+
+// the magic string at the beginning of every bytecode produced by [Context.Compile].
+const bytecodeMagic = "qjbc"
+
+// the kinds of compiled code, stored in the bytecode header.
+const (
+	bytecodeKindScript byte = 0
+	bytecodeKindModule byte = 1
+)
+
+// returned by [Context.EvalBytecode] and [Context.Deserialize] when the data was produced by a different quickjs version (or is not our data at all).
+var ErrBytecodeVersion = errors.New("incompatible bytecode")
+
+// compile javascript source `code` to bytecode, which can later be evaluated via [Context.EvalBytecode] (even in a different process).
+//
+// the `name` is used as the file name in stack traces, and when `module` is `true`, the code is compiled as an es-module,
+// in which case its name also serves as the base for resolving its relative imports.
+//
+// > [!note]
+// > compiling an es-module also resolves (and compiles) all of its imports through the runtime's [ModuleLoader],
+// > however, the imported modules are _not_ included in the returned bytecode; they will be loaded again when the bytecode is evaluated.
+func (ctx *Context) Compile(name, code string, module bool) ([]byte, error) {
+	if ctx.ref == nil {
+		return nil, errors.New("context is nil")
+	}
+	eval_type, kind := js_EVAL_TYPE_GLOBAL_COMPILE, bytecodeKindScript
+	if module {
+		eval_type, kind = js_EVAL_TYPE_MODULE_COMPILE, bytecodeKindModule
+	}
+	func_val := ctx.compileOnly(name, code, eval_type)
+	if C.JS_IsException(func_val) != 0 {
+		return nil, ctx.takeException()
+	}
+	defer C.JS_FreeValue(ctx.ref, func_val)
+	var c_size C.size_t
+	c_buf := C.JS_WriteObject(ctx.ref, &c_size, func_val, C.JS_WRITE_OBJ_BYTECODE)
+	if c_buf == nil {
+		return nil, ctx.takeException()
+	}
+	defer C.js_free(ctx.ref, unsafe.Pointer(c_buf))
+
+	header := newVersionHeader(bytecodeMagic, kind)
+	bytecode := make([]byte, len(header), len(header)+int(c_size))
+	copy(bytecode, header)
+	return append(bytecode, unsafe.Slice((*byte)(unsafe.Pointer(c_buf)), int(c_size))...), nil
+}
+
+// evaluate the `bytecode` produced by [Context.Compile].
+//
+// for a compiled script, the result of its last statement is returned (just like [Context.Eval]),
+// while for a compiled es-module, its namespace object is returned (just like [Context.EvalModule]).
+//
+// if the bytecode was produced by a different quickjs version, an [ErrBytecodeVersion] error is returned.
+//
+// @should-free
+func (ctx *Context) EvalBytecode(bytecode []byte) (*Value, error) {
+	if ctx.ref == nil {
+		return nil, errors.New("context is nil")
+	}
+	body, kind, err := parseVersionHeader(bytecode, bytecodeMagic)
+	if err != nil {
+		return nil, err
+	}
+	if kind != bytecodeKindScript && kind != bytecodeKindModule {
+		return nil, fmt.Errorf("%w: unknown kind of compiled code: %d", ErrBytecodeVersion, kind)
+	}
+	if len(body) == 0 {
+		return nil, fmt.Errorf("%w: the bytecode is empty", ErrBytecodeVersion)
+	}
+	func_val := C.JS_ReadObject(ctx.ref, (*C.uint8_t)(unsafe.Pointer(&body[0])), C.size_t(len(body)), C.JS_READ_OBJ_BYTECODE)
+	if C.JS_IsException(func_val) != 0 {
+		return nil, ctx.takeException()
+	}
+	if kind == bytecodeKindModule {
+		// unlike freshly compiled modules, the imports of deserialized modules must be resolved manually.
+		if C.JS_ResolveModule(ctx.ref, func_val) < 0 {
+			C.JS_FreeValue(ctx.ref, func_val)
+			return nil, ctx.takeException()
+		}
+		return ctx.evalModuleFunction(func_val)
+	}
+	ctx.rt.enter()
+	defer ctx.rt.exit()
+	// `JS_EvalFunction` consumes the compiled script.
+	result := ctx.newValue(C.JS_EvalFunction(ctx.ref, func_val))
+	if result.IsException() {
+		return nil, ctx.takeException()
+	}
+	return result, nil
+}
+
+// create the header that precedes our bytecode (and serialized values), with the given `magic` string and `kind` byte.
+func newVersionHeader(magic string, kind byte) []byte {
+	header := make([]byte, 0, len(magic)+len(QuickjsVersion)+2)
+	header = append(header, magic...)
+	header = append(header, byte(len(QuickjsVersion)))
+	header = append(header, QuickjsVersion...)
+	return append(header, kind)
+}
+
+// validate the header of the `data` (see [newVersionHeader]), and return the quickjs data that follows it, along with the header's kind byte.
+func parseVersionHeader(data []byte, magic string) (body []byte, kind byte, err error) {
+	if !bytes.HasPrefix(data, []byte(magic)) || len(data) < len(magic)+1 {
+		return nil, 0, fmt.Errorf("%w: missing the header", ErrBytecodeVersion)
+	}
+	rest := data[len(magic):]
+	version_len := int(rest[0])
+	rest = rest[1:]
+	if len(rest) < version_len+1 {
+		return nil, 0, fmt.Errorf("%w: truncated header", ErrBytecodeVersion)
+	}
+	if version := string(rest[:version_len]); version != QuickjsVersion {
+		return nil, 0, fmt.Errorf(`%w: produced by quickjs version "%s", but this is version "%s"`, ErrBytecodeVersion, version, QuickjsVersion)
+	}
+	return rest[version_len+1:], rest[version_len], nil
+}
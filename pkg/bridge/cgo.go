@@ -33,5 +33,7 @@ package bridge
 
 // in each file that shall reference quickjs c-constructs, we must use the following include statement to include its header file:
 // #include "./include0_quickjs.h"
+// or, if the file also needs the small c-side helper functions (such as casting cgo handles to opaque pointers), use this one instead:
+// #include "./include1_helpers.h"
 */
 import "C"
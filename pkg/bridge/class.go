@@ -0,0 +1,227 @@
+// this file contains the wrapper for registering go-backed javascript classes,
+// whose instances carry an opaque go-value, which is released by a finalizer once quickjs garbage collects the instance.
+
+package bridge
+
+/*
+#include "./include1_helpers.h"
+
+// forward declarations of the class callback functions, otherwise the compiler won't discover them.
+JSClassFinalizer goClassFinalizer;
+JSCFunctionMagic goClassConstructorTrampoline;
+
+// create the constructor function of a go-backed class, whose `magic` is its index in the context's list of class constructors.
+// unlike the functions of `JS_NewCFunctionData`, a "constructor or func" receives `new.target` only when it is called via `new`,
+// and `undefined` otherwise (cgo cannot cast between function pointer types, hence why this is done in c).
+static inline JSValue newClassConstructor(JSContext *ctx, const char *name, int length, int magic) {
+	return JS_NewCFunction2(ctx, (JSCFunction*)goClassConstructorTrampoline, name, length, JS_CFUNC_constructor_or_func_magic, magic);
+}
+*/
+import "C"
+import (
+	fmt "fmt"
+	math "math"
+	cgo "runtime/cgo"
+	slices "slices"
+	unsafe "unsafe"
+)
+
+// describes a go-backed javascript class, to be registered via [Runtime.NewClass].
+type ClassDefinition struct {
+	// the name of the javascript class (it will also be the name of its constructor function).
+	Name string
+	// the `length` property of the constructor function (i.e. the number of arguments that it expects).
+	Length int
+	// creates the go-data that will be stored inside of a new instance, when the class is constructed from javascript via `new`.
+	// the `args` are _borrowed_, so you must not free them.
+	//
+	// if left `nil`, then the class can only be instantiated from go (via [Context.NewClassInstance]),
+	// and calling `new` on it will throw an `Error`.
+	Constructor func(ctx *Context, args []*Value) (any, error)
+	// the methods to place on the class's prototype.
+	// inside each method, you can acquire the instance's go-data via `this.Opaque()`.
+	Methods map[string]GoFunction
+	// an optional callback that is executed when an instance is garbage collected by quickjs, receiving the instance's go-data.
+	//
+	// > [!important]
+	// > the finalizer runs in the middle of quickjs's garbage collection, so you must **not** interact with any javascript [Value] inside of it.
+	Finalizer func(data any)
+}
+
+// represents a go-backed javascript class that has been registered to a [Runtime].
+//
+// since quickjs class ids are runtime-wide, a single [Class] can be defined in any [Context] of its [Runtime] via [Context.DefineClass].
+type Class struct {
+	rt  *Runtime
+	id  C.JSClassID
+	def ClassDefinition
+}
+
+// the data behind an instance's opaque [cgo.Handle].
+type classInstance struct {
+	class *Class
+	data  any
+}
+
+//export goClassFinalizer
+func goClassFinalizer(rt_ref *C.JSRuntime, val C.JSValue) {
+	opaque := C.JS_GetOpaque(val, C.JS_GetClassID(val))
+	if opaque == nil {
+		return
+	}
+	handle := cgo.Handle(C.opaqueToHandle(opaque))
+	instance := handle.Value().(*classInstance)
+	handle.Delete()
+	if finalizer := instance.class.def.Finalizer; finalizer != nil {
+		finalizer(instance.data)
+	}
+}
+
+//export goClassConstructorTrampoline
+func goClassConstructorTrampoline(ctx_ref *C.JSContext, new_target C.JSValue, args_len C.int, first_arg_ptr *C.JSValue, magic C.int) C.JSValue {
+	ctx := contextFromRef(ctx_ref)
+	return ctx.callGoFunction(ctx.classConstructors[magic], new_target, args_len, first_arg_ptr)
+}
+
+// register a new go-backed javascript class to the runtime.
+//
+// the returned [Class] does not become visible to javascript until you call [Context.DefineClass] with it.
+func (rt *Runtime) NewClass(def ClassDefinition) *Class {
+	cls := &Class{rt: rt, def: def}
+	C.JS_NewClassID(&cls.id)
+	cstr_ptr := C.CString(def.Name)
+	// the class name is copied into an atom by quickjs, so we are free to release our c-string afterwards.
+	defer C.free(unsafe.Pointer(cstr_ptr))
+	class_def := C.JSClassDef{class_name: cstr_ptr, finalizer: &C.goClassFinalizer}
+	if C.JS_NewClass(rt.ref, cls.id, &class_def) != 0 {
+		panic(fmt.Sprintf(`[Runtime.NewClass]: failed to register the class: "%s".`, def.Name))
+	}
+	rt.classes[cls.id] = cls
+	return cls
+}
+
+// get the name of the class.
+func (cls *Class) Name() string {
+	return cls.def.Name
+}
+
+// define a go-backed [Class] in this context, by creating its prototype (carrying the class's methods) and its constructor function.
+// the returned constructor is _not_ assigned to `globalThis`, so you will have to do that yourself if it is desired.
+//
+// this method must be called before creating any instances of the class in this context via [Context.NewClassInstance].
+//
+// @should-free
+func (ctx *Context) DefineClass(cls *Class) *Value {
+	if cls.rt != ctx.rt {
+		panic(fmt.Sprintf(`[Context.DefineClass]: the class "%s" belongs to a different runtime.`, cls.def.Name))
+	}
+	proto := ctx.NewObject()
+	// we sort the method names so that the order of the prototype's properties remains deterministic.
+	method_names := make([]string, 0, len(cls.def.Methods))
+	for name := range cls.def.Methods {
+		method_names = append(method_names, name)
+	}
+	slices.Sort(method_names)
+	for _, name := range method_names {
+		method := ctx.NewFunction(name, 0, cls.def.Methods[name])
+		// just like javascript class methods, our methods are non-enumerable.
+		proto.define(name, method, C.JS_PROP_CONFIGURABLE|C.JS_PROP_WRITABLE)
+	}
+	construct := func(ctx *Context, new_target *Value, args []*Value) (*Value, error) {
+		// quickjs passes `new.target` in place of `this`, which is `undefined` when the constructor is called without `new`
+		// (even when it is called with a `this` object, such as via `Ctor.call(Object.create(Ctor.prototype))`).
+		if !new_target.IsConstructor() {
+			return nil, fmt.Errorf(`class constructor "%s" cannot be invoked without "new"`, cls.def.Name)
+		}
+		if cls.def.Constructor == nil {
+			return nil, fmt.Errorf(`class "%s" cannot be constructed from javascript`, cls.def.Name)
+		}
+		data, err := cls.def.Constructor(ctx, args)
+		if err != nil {
+			return nil, err
+		}
+		// subclasses will have a different `new.target`, hence why we don't just use the class's default prototype.
+		instance_proto := new_target.Get("prototype")
+		defer instance_proto.Free()
+		return ctx.newClassInstanceProto(cls, instance_proto, data), nil
+	}
+	// quickjs stores the magic in 16 bits.
+	magic := len(ctx.classConstructors)
+	if magic > math.MaxInt16 {
+		panic(fmt.Sprintf(`[Context.DefineClass]: too many classes have been defined in this context, while defining: "%s".`, cls.def.Name))
+	}
+	ctx.classConstructors = append(ctx.classConstructors, construct)
+	c_name := C.CString(cls.def.Name)
+	defer C.free(unsafe.Pointer(c_name))
+	ctor := ctx.newValue(C.newClassConstructor(ctx.ref, c_name, C.int(cls.def.Length), C.int(magic)))
+	// this links `ctor.prototype = proto` and `proto.constructor = ctor`.
+	C.JS_SetConstructor(ctx.ref, ctor.ref, proto.ref)
+	// the class prototype takes ownership of `proto`, so we must not free it.
+	C.JS_SetClassProto(ctx.ref, cls.id, proto.transfer())
+	return ctor
+}
+
+// create a new instance of a go-backed [Class], carrying the given go-`data`.
+//
+// make sure that you have already called [Context.DefineClass] on this context, otherwise the instance will lack a prototype.
+//
+// @should-free
+func (ctx *Context) NewClassInstance(cls *Class, data any) *Value {
+	instance := ctx.newValue(C.JS_NewObjectClass(ctx.ref, C.int(cls.id)))
+	instance.setClassInstanceData(cls, data)
+	return instance
+}
+
+// same as [Context.NewClassInstance], but with a custom prototype (needed for subclassing).
+//
+// @should-free
+func (ctx *Context) newClassInstanceProto(cls *Class, proto *Value, data any) *Value {
+	instance := ctx.newValue(C.JS_NewObjectProtoClass(ctx.ref, proto.ref, cls.id))
+	instance.setClassInstanceData(cls, data)
+	return instance
+}
+
+func (instance *Value) setClassInstanceData(cls *Class, data any) {
+	if instance.IsException() {
+		return
+	}
+	handle := cgo.NewHandle(&classInstance{class: cls, data: data})
+	C.JS_SetOpaque(instance.ref, C.handleToOpaque(C.uintptr_t(handle)))
+}
+
+// get the [classInstance] stored inside of a go-backed class instance, or `nil` if the value is not one.
+func (val *Value) classInstance() *classInstance {
+	if !val.IsObject() {
+		return nil
+	}
+	// builtin classes also store their internal state in the same slot as the opaque pointer,
+	// so we must only ever interpret the opaque pointers of our own go-backed classes as handles.
+	class_id := C.JS_GetClassID(val.ref)
+	if _, ok := val.ctx.rt.classes[class_id]; !ok {
+		return nil
+	}
+	opaque := C.JS_GetOpaque(val.ref, class_id)
+	if opaque == nil {
+		return nil
+	}
+	return cgo.Handle(C.opaqueToHandle(opaque)).Value().(*classInstance)
+}
+
+// get the go-data stored inside of a go-backed class instance (see [Runtime.NewClass]).
+//
+// if the value is not an instance of a go-backed class, then `nil` will be returned.
+func (val *Value) Opaque() any {
+	instance := val.classInstance()
+	if instance == nil {
+		return nil
+	}
+	return instance.data
+}
+
+// check if the value is an instance of the given go-backed [Class].
+//
+// unlike [Value.IsInstanceOf], this check does not walk the prototype chain,
+// but instead inspects the internal class id of the object, thus it can't be fooled by `Object.setPrototypeOf`.
+func (val *Value) IsInstanceOfClass(cls *Class) bool {
+	return val.IsObject() && C.JS_GetClassID(val.ref) == cls.id
+}
@@ -0,0 +1,86 @@
+// this file contains the [Clock] interface, which the event loop uses for measuring time and for waiting on timers,
+// along with a [FakeClock] implementation that lets tests control the passage of time deterministically.
+
+package bridge
+
+import (
+	sync "sync"
+	time "time"
+)
+
+// a source of time for the timers of a runtime's event loop (see [Runtime.SetClock]).
+type Clock interface {
+	// get the current time.
+	Now() time.Time
+	// get a channel that receives the current time once the duration `d` has elapsed.
+	After(d time.Duration) <-chan time.Time
+}
+
+// the default [Clock], which is backed by the system's time.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// a [Clock] whose time only moves forward when [FakeClock.Advance] is called.
+//
+// it is intended for testing code that uses timers, without having to wait in real time.
+// for example, after advancing the clock, use [Runtime.RunReady] to execute the timers that became due:
+//
+//	clock := bridge.NewFakeClock(time.Unix(0, 0))
+//	rt.SetClock(clock)
+//	ctx.Eval(`setTimeout(() => console.log("done"), 1000)`)
+//	clock.Advance(time.Second)
+//	rt.RunReady() // executes the timeout's callback.
+type FakeClock struct {
+	mutex   sync.Mutex
+	now     time.Time
+	waiters []fakeClockWaiter
+}
+
+type fakeClockWaiter struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+// create a fake clock that starts at the time `start`.
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{now: start}
+}
+
+func (clock *FakeClock) Now() time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	return clock.now
+}
+
+func (clock *FakeClock) After(d time.Duration) <-chan time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	// the channel is buffered, so that advancing the clock never blocks on a waiter that has given up.
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- clock.now
+		return ch
+	}
+	clock.waiters = append(clock.waiters, fakeClockWaiter{deadline: clock.now.Add(d), ch: ch})
+	return ch
+}
+
+// move the clock forward by the duration `d`, and notify all waiters whose deadline has been reached.
+//
+// this method is safe to call from any goroutine.
+func (clock *FakeClock) Advance(d time.Duration) {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	clock.now = clock.now.Add(d)
+	remaining := clock.waiters[:0]
+	for _, waiter := range clock.waiters {
+		if waiter.deadline.After(clock.now) {
+			remaining = append(remaining, waiter)
+			continue
+		}
+		waiter.ch <- clock.now
+	}
+	clock.waiters = remaining
+}
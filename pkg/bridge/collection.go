@@ -43,6 +43,11 @@ func (arr *Value) IsArrayBuffer() bool {
 	return arr.IsInstanceOf(arr.ctx.valueCache.arrayBuffer)
 }
 
+// test if your value is an instance of a `SharedArrayBuffer` (see `./shared.go`).
+func (arr *Value) IsSharedArrayBuffer() bool {
+	return arr.IsInstanceOf(arr.ctx.valueCache.sharedArrayBuffer)
+}
+
 // test if your value is an instance of a certain kind of `TypedArray`, specified by the `kind“ ([TypedArrayEnum]) enum option.
 // if you would like to check if your value is _any_ kind of typed array, use the [TypedArrayAny] enum option (`-1`).
 //
@@ -145,9 +150,9 @@ func (arr *Value) IdentifyTypedArrayInfo() TypedArrayInfo {
 		byte_length       C.size_t
 		bytes_per_element C.size_t
 	)
-	buffer_ref := C.JS_GetTypedArrayBuffer(arr.ctx.ref, arr.ref, &byte_offset, &byte_length, &bytes_per_element)
-	buffer := &Value{ctx: arr.ctx, ref: buffer_ref}
-	if buffer.IsArrayBuffer() {
+	buffer_ref := C.JS_GetTypedArrayBuffer(arr.cctx(), arr.ref, &byte_offset, &byte_length, &bytes_per_element)
+	buffer := arr.ctx.newValue(buffer_ref)
+	if buffer.IsArrayBuffer() || buffer.IsSharedArrayBuffer() {
 		return TypedArrayInfo{
 			Buffer:          buffer,
 			ByteOffset:      uint(byte_offset),
@@ -171,7 +176,7 @@ func (ctx *Context) NewTypedArray(kind TypedArrayEnum, length uint32) *Value {
 	js_length_int := ctx.NewUint32(length)
 	// equivalent to the js-signature: `new TypedArray(length)`
 	js_arr_ref := C.JS_NewTypedArray(ctx.ref, 1, &js_length_int.ref, js_kind)
-	return &Value{ctx: ctx, ref: js_arr_ref}
+	return ctx.newValue(js_arr_ref)
 }
 
 // create a new javascript typed array, by copying over the `raw_data` bytes to it.
@@ -193,7 +198,7 @@ func (ctx *Context) NewTypedArrayFromArrayBuffer(kind TypedArrayEnum, js_array_b
 	js_kind := C.JSTypedArrayEnum(kind)
 	// equivalent to the js-signature: `new TypedArray(buffer)`
 	js_arr_ref := C.JS_NewTypedArray(ctx.ref, 1, &js_array_buffer.ref, js_kind)
-	return &Value{ctx: ctx, ref: js_arr_ref}
+	return ctx.newValue(js_arr_ref)
 }
 
 // create a new javascript `ArrayBuffer` by copying over the `raw_data` bytes to it.
@@ -211,15 +216,7 @@ func (ctx *Context) NewArrayBuffer(raw_data []byte) *Value {
 		first_byte_ptr = &raw_data[0]
 	}
 	js_arr_ref := C.JS_NewArrayBufferCopy(ctx.ref, (*C.uint8_t)(first_byte_ptr), C.size_t(raw_data_len))
-	return &Value{ctx: ctx, ref: js_arr_ref}
-}
-
-//export sharedArrayBufferFreeFunc
-func sharedArrayBufferFreeFunc(rt *C.JSRuntime, opaque_pinner unsafe.Pointer, data_first_byte_ptr unsafe.Pointer) {
-	if opaque_pinner != nil {
-		pinner := (*runtime.Pinner)(opaque_pinner)
-		pinner.Unpin() // unpinning the memory region, so that the go runtime can garbage collect it whenever.
-	}
+	return ctx.newValue(js_arr_ref)
 }
 
 // create a new javascript `ArrayBuffer` that shares its memory with the provided `raw_data` slice.
@@ -246,12 +243,15 @@ func (ctx *Context) NewArrayBufferShared(raw_data []byte) *Value {
 	// this process is known as "pinning" the memory region. and freeing it up is known as "unpinning" the region.
 	pinner := &runtime.Pinner{}
 	pinner.Pin(first_byte_ptr)
+	// the pinned memory is registered as a shared block (see `./shared.go`), so that it can also be shared with other runtimes.
+	// once the last reference to it is released, the memory gets unpinned, so that the go runtime can garbage collect it whenever.
+	registerSharedBlock(unsafe.Pointer(first_byte_ptr), raw_data_len, pinner.Unpin)
 	js_arr_ref := C.JS_NewArrayBuffer(
 		ctx.ref, (*C.uint8_t)(first_byte_ptr), C.size_t(raw_data_len),
-		&C.sharedArrayBufferFreeFunc, unsafe.Pointer(pinner), (C.JS_BOOL)(1),
+		&C.sharedArrayBufferFreeFunc, nil, (C.JS_BOOL)(1),
 	)
-	js_arr := &Value{ctx: ctx, ref: js_arr_ref}
-	// memory free up trajectory: `js_arr.Free()` -> `C.JS_FreeValue(...)` -> `C.sharedArrayBufferFreeFunc(...)` -> `sharedArrayBufferFreeFunc(...)` -> done
+	js_arr := ctx.newValue(js_arr_ref)
+	// memory free up trajectory: `js_arr.Free()` -> `C.JS_FreeValue(...)` -> `goSharedBufferFree(...)` -> `freeSharedBlock(...)` -> `pinner.Unpin()` -> done
 	return js_arr
 }
 
@@ -287,7 +287,7 @@ func (arr *Value) ToByteArray() []byte {
 // so that you do not end up with the dangling pointer situation.
 func (arr *Value) ToByteArrayShared() []byte {
 	var typed_info TypedArrayInfo
-	is_array_buffer := arr.IsArrayBuffer()
+	is_array_buffer := arr.IsArrayBuffer() || arr.IsSharedArrayBuffer()
 	if is_array_buffer {
 		typed_info.Buffer = arr
 		typed_info.ByteOffset = 0
@@ -298,7 +298,7 @@ func (arr *Value) ToByteArrayShared() []byte {
 		defer typed_info.Buffer.Free()
 	}
 	var buf_length C.size_t
-	first_buf_byte_ptr := C.JS_GetArrayBuffer(typed_info.Buffer.ctx.ref, &buf_length, typed_info.Buffer.ref)
+	first_buf_byte_ptr := C.JS_GetArrayBuffer(typed_info.Buffer.cctx(), &buf_length, typed_info.Buffer.ref)
 	// if the buffer's length is zero, then `first_byte_ptr` will likely be `nil`, so we will return an empty slice.
 	if buf_length == 0 {
 		return []byte{}
@@ -312,7 +312,7 @@ func (arr *Value) ToByteArrayShared() []byte {
 	return shared_byte_slice
 }
 
-// - [ ] TODO: other miscellaneous classes to add support for: `Date`, `RegExp`, `JSON`, `Promise`.
+// - [ ] TODO: other miscellaneous classes to add support for: `RegExp`, `JSON`, `Promise`. (`Date` is covered by `./date.go`)
 // - [ ] TODO: classes to consider adding support for if they're not too difficult to implement: `GeneratorFunction`, `AsyncGeneratorFunction`, `Proxy`, `Reflect`.
 // - [x] TODO: add the `IsArray`, `IsHashMap`, and `IsHashSet` functions.
 // - [x] TODO: add the `NewArray`, `NewHashMap`, and `NewHashSet` functions.
@@ -320,7 +320,7 @@ func (arr *Value) ToByteArrayShared() []byte {
 
 //------      TYPE CHECKS      ------//
 
-func (val *Value) IsArray() bool   { return val != nil && C.JS_IsArray(val.ctx.ref, val.ref) == 1 }
+func (val *Value) IsArray() bool   { return val != nil && C.JS_IsArray(val.cctx(), val.ref) == 1 }
 func (val *Value) IsHashMap() bool { return val.IsInstanceOf(val.ctx.valueCache.hashMap) }
 func (val *Value) IsHashSet() bool { return val.IsInstanceOf(val.ctx.valueCache.hashSet) }
 func (val *Value) IsWeakMap() bool { return val.IsInstanceOf(val.ctx.valueCache.weakMap) }
@@ -352,7 +352,7 @@ func (val *Value) IsWeakSet() bool { return val.IsInstanceOf(val.ctx.valueCache.
 // create a new javascript `Array` object.
 func (ctx *Context) NewArray() *Value {
 	ref := C.JS_NewArray(ctx.ref)
-	return &Value{ctx: ctx, ref: ref}
+	return ctx.newValue(ref)
 }
 
 // create a new javascript `Map` object.
@@ -1,5 +1,5 @@
 //go:build quiccjs_release
-#define CONFIG_VERSION "2025-09-13"
+#include "./include2_version.h"
 #define _GNU_SOURCE
 
 #include "./../../vendor/quickjs/quickjs.c"
@@ -1,29 +1,47 @@
 package bridge
 
 /*
-#include "./include0_quickjs.h"
+#include "./include1_helpers.h"
 */
 import "C"
 import (
 	errors "errors"
 	fmt "fmt"
-	runtime "runtime"
+	cgo "runtime/cgo"
 	unsafe "unsafe"
 )
 
 type Context struct {
-	rt              *Runtime
-	ref             *C.JSContext
-	atomCache       contextAtomCache
-	valueCache      contextValueCache
-	atomFreeupList  []*Atom
-	valueFreeupList []*Value
+	rt         *Runtime
+	ref        *C.JSContext
+	atomCache  contextAtomCache
+	valueCache contextValueCache
+	// the innermost active [Scope] (see [Context.WithScope]), which records the newly created values and atoms, or `nil` if there is none.
+	scope *Scope
+	// the context's own scope, which frees the values and atoms marked via [Value.FreeOnExit] when the context is freed.
+	exitScope *Scope
+	// the go-implemented es-modules registered via [Context.RegisterNativeModule], keyed by their module names.
+	nativeModules map[string]*nativeModule
+	// the promises created via [Context.NewPromise] that have not been settled yet, whose resolving functions are freed when the context is freed.
+	pendingPromises map[*pendingPromise]struct{}
+	// the constructors of the go-backed classes defined in this context (see [Context.DefineClass]), indexed by the magic of their javascript functions.
+	classConstructors []GoFunction
+	// the workers spawned by this context (see [Context.RegisterWorkers]) that are still alive, which are terminated when the context is freed.
+	workers map[*worker]struct{}
+	// dictates whether thrown values that are not `Error`s are kept in the returned errors (see [Context.SetCaptureThrown]).
+	captureThrown bool
+	// the handle to this very context, which is stored as the c-context's opaque data, so that quickjs callbacks can find their way back to it.
+	handle cgo.Handle
+	// the tracker of the values and atoms owned by go, which only records anything in debug builds (see `./leaks.go`).
+	leaks leakTracker
 }
 
 type contextAtomCache struct {
 	length     *Atom
 	size       *Atom
 	byteLength *Atom
+	// lazily created atoms
+	goError *Atom // see [Context.goErrorAtom].
 }
 
 type contextValueCache struct {
@@ -41,6 +59,7 @@ type contextValueCache struct {
 	weakSet *Value
 	// typed arrays and buffers
 	arrayBuffer       *Value
+	sharedArrayBuffer *Value
 	typedArray        *Value
 	uint8Array        *Value
 	uint16Array       *Value
@@ -54,6 +73,8 @@ type contextValueCache struct {
 	float16Array      *Value
 	float32Array      *Value
 	float64Array      *Value
+	// helpers
+	serializeCodec *Value // see [Context.injectSerializeCodec].
 }
 
 func (rt *Runtime) NewContext() *Context {
@@ -65,29 +86,46 @@ func (rt *Runtime) NewContext() *Context {
 		rt:              rt,
 		atomCache:       contextAtomCache{},
 		valueCache:      contextValueCache{},
-		atomFreeupList:  []*Atom{},
-		valueFreeupList: []*Value{},
+		nativeModules:   map[string]*nativeModule{},
+		pendingPromises: map[*pendingPromise]struct{}{},
 	}
 	if ctx.ref == nil {
 		return nil
 	}
+	ctx.exitScope = &Scope{ctx: ctx}
+	// note that this self-referencing handle keeps the context reachable until [Context.Free] is called.
+	ctx.handle = cgo.NewHandle(ctx)
+	C.JS_SetContextOpaque(ctx.ref, C.handleToOpaque(C.uintptr_t(ctx.handle)))
 	ctx.injectAtomCache()
 	ctx.injectValueCache()
-	// TODO: I'm unsure if we should be cleaning it up automatically, or if it should be the end user's responsibility.
-	runtime.AddCleanup(ctx, (*Context).Free, nil)
+	ctx.injectSerializeCodec()
+	ctx.injectAtomics()
 	return ctx
 }
 
+// free the context, along with the values and atoms marked via [Value.FreeOnExit] and [Atom.FreeOnExit].
+// just like runtimes, contexts are never freed automatically by go's garbage collector (see [Runtime.Free]).
 func (ctx *Context) Free() {
 	if ctx.ref != nil {
-		for _, val := range ctx.valueFreeupList {
-			val.Free()
+		ctx.terminateWorkers()
+		ctx.exitScope.exit()
+		for pending := range ctx.pendingPromises {
+			pending.free()
 		}
+		ctx.rt.loop.forgetContext(ctx)
+		// by now, everything that the context itself owns has been released, so whatever remains must have been leaked.
+		ctx.reportLeaks()
 		C.JS_FreeContext(ctx.ref)
 		ctx.ref = nil
+		ctx.handle.Delete()
 	}
 }
 
+// get the go [Context] of a c-context. this is needed inside of callbacks that are invoked by quickjs with nothing but the c-context.
+func contextFromRef(ctx_ref *C.JSContext) *Context {
+	return cgo.Handle(C.opaqueToHandle(C.JS_GetContextOpaque(ctx_ref))).Value().(*Context)
+}
+
 func (ctx *Context) injectAtomCache() {
 	create_atom := func(atom_name string) *Atom {
 		atom := ctx.NewAtom(atom_name)
@@ -103,7 +141,7 @@ func (ctx *Context) injectAtomCache() {
 }
 
 func (ctx *Context) injectValueCache() {
-	global_this := &Value{ctx: ctx, ref: C.JS_GetGlobalObject(ctx.ref)}
+	global_this := ctx.newValue(C.JS_GetGlobalObject(ctx.ref))
 	ctx.valueCache.globalThis = global_this
 	global_this.FreeOnExit()
 
@@ -128,6 +166,7 @@ func (ctx *Context) injectValueCache() {
 	ctx.valueCache.weakSet = get_obj("WeakSet")
 	// typed arrays and buffers
 	ctx.valueCache.arrayBuffer = get_obj("ArrayBuffer")
+	ctx.valueCache.sharedArrayBuffer = get_obj("SharedArrayBuffer")
 	ctx.valueCache.uint8Array = get_obj("Uint8Array")
 	ctx.valueCache.uint16Array = get_obj("Uint16Array")
 	ctx.valueCache.uint32Array = get_obj("Uint32Array")
@@ -149,12 +188,27 @@ type js_EVAL_TYPE int // `0` or `1`
 const (
 	js_EVAL_TYPE_GLOBAL js_EVAL_TYPE = C.JS_EVAL_TYPE_GLOBAL // 0
 	js_EVAL_TYPE_ASYNC  js_EVAL_TYPE = C.JS_EVAL_TYPE_GLOBAL | C.JS_EVAL_FLAG_ASYNC
+	// compiles a script without evaluating it, so that it can be serialized to bytecode.
+	js_EVAL_TYPE_GLOBAL_COMPILE js_EVAL_TYPE = C.JS_EVAL_TYPE_GLOBAL | C.JS_EVAL_FLAG_COMPILE_ONLY
+	// compiles an es-module without evaluating it, so that its `JSModuleDef` can be acquired before running it.
+	js_EVAL_TYPE_MODULE_COMPILE js_EVAL_TYPE = C.JS_EVAL_TYPE_MODULE | C.JS_EVAL_FLAG_COMPILE_ONLY
 )
 
+// evaluate the javascript `code` in the global scope, and return its completion value.
+//
+// if the code throws, the exception is returned as an [*Error] (use [errors.As] to access its `Name`, `Stack`, etc...).
+//
+// @should-free
 func (ctx *Context) Eval(code string) (*Value, error) {
 	return ctx.evalBase(code, js_EVAL_TYPE_GLOBAL)
 }
 
+// evaluate the javascript `code` in the global scope, while permitting top-level `await`s.
+// the returned value is a promise of an object, whose `value` property holds the completion value of the code.
+//
+// just like with [Context.Eval], a synchronously thrown exception is returned as an [*Error].
+//
+// @should-free
 func (ctx *Context) EvalAsync(code string) (*Value, error) {
 	return ctx.evalBase(code, js_EVAL_TYPE_ASYNC)
 }
@@ -163,6 +217,9 @@ func (ctx *Context) evalBase(code string, eval_type js_EVAL_TYPE) (*Value, error
 	if ctx.ref == nil {
 		return nil, errors.New("context is nil")
 	}
+	ctx.rt.assertOwner()
+	ctx.rt.enter()
+	defer ctx.rt.exit()
 	c_code := C.CString(code)
 	c_filename := C.CString("<eval>") // I don't think it's possible to perform an eval without a file name.
 	c_code_len := C.size_t(len(code)) // this is not `len(code) + 1` because the terminating null character must not be included in the code.
@@ -172,11 +229,14 @@ func (ctx *Context) evalBase(code string, eval_type js_EVAL_TYPE) (*Value, error
 
 	result := C.JS_Eval(ctx.ref, c_code, c_code_len, c_filename, c_eval_flag)
 	if C.JS_IsException(result) != 0 {
-		val := Value{ref: C.JS_GetException(ctx.ref), ctx: ctx}
-		defer val.Free()
-		println(val.ToString())
-		return nil, errors.New("JS exception: " + val.ToString())
+		return nil, ctx.takeException()
 	}
-	val := Value{ref: result, ctx: ctx}
-	return &val, nil
+	return ctx.newValue(result), nil
+}
+
+// take the pending exception out of the context (thereby clearing it), and convert it to an [*Error].
+func (ctx *Context) takeException() error {
+	val := ctx.newValue(C.JS_GetException(ctx.ref))
+	defer val.Free()
+	return val.toThrownError()
 }
@@ -0,0 +1,39 @@
+// this file contains functions for creating and converting javascript `Date` objects.
+
+package bridge
+
+/*
+#include "./include0_quickjs.h"
+*/
+import "C"
+import (
+	math "math"
+	time "time"
+)
+
+// test if your value is an instance of a `Date`.
+func (val *Value) IsDate() bool {
+	return val.IsInstanceOf(val.ctx.valueCache.date)
+}
+
+// create a new javascript `Date` object from a go [time.Time].
+//
+// note that javascript dates only have a millisecond precision, so any finer precision will be truncated.
+//
+// @should-free
+func (ctx *Context) NewDate(t time.Time) *Value {
+	return ctx.newValue(C.JS_NewDate(ctx.ref, C.double(t.UnixMilli())))
+}
+
+// returns the go [time.Time] representation of a javascript `Date` (in the local time zone).
+//
+// if the date is invalid (i.e. `date.getTime()` is `NaN`), then the zero [time.Time] will be returned.
+func (val *Value) ToTime() time.Time {
+	epoch_ms := val.CallMethod("getTime")
+	defer epoch_ms.Free()
+	ms := epoch_ms.ToFloat64()
+	if math.IsNaN(ms) {
+		return time.Time{}
+	}
+	return time.UnixMilli(int64(ms))
+}
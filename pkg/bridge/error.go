@@ -1,4 +1,12 @@
 // this file contains a wrapper for javascript's `Error` class instances.
+//
+// go `error`s thrown to javascript (see [Context.NewError]) keep the original go `error` in an instance of the runtime's opaque "GoError" class,
+// which is stored in a read-only property of the javascript `Error`, keyed by a symbol that is unique to the context.
+// so when such an exception bubbles back up to go, the resulting [*Error] unwraps to the original go `error` (see [Error.Unwrap]).
+//
+// note that the symbol is an ordinary one (quickjs offers no private symbols to embedders), so scripts can still discover the property
+// via `Object.getOwnPropertySymbols`. however, they can neither overwrite nor delete it, and all they get to see is the opaque holder,
+// since the go `error` itself never leaves go.
 
 package bridge
 
@@ -6,7 +14,10 @@ package bridge
 #include "./include0_quickjs.h"
 */
 import "C"
-import fmt "fmt"
+import (
+	fmt "fmt"
+	debug "runtime/debug"
+)
 
 // represents a quickjs error formatted for go, in addition to also implementing the `error` go interface.
 type Error struct {
@@ -14,17 +25,73 @@ type Error struct {
 	Message string // the error message.
 	Cause   string // the cause behind the error.
 	Stack   string // the stack trace before the error.
+	// the thrown javascript value, when it is not an `Error` instance (for instance, `42` after a `throw 42`), otherwise `nil`.
+	// in such cases, the `Message` holds the string representation of the value, while the other fields remain empty.
+	//
+	// the value is only captured when enabled via [Context.SetCaptureThrown], in which case the caller owns it,
+	// and must release it via [Error.Free] (or take it over and free it on their own) before its context is freed.
+	Thrown *Value
+	// the original go `error` that was thrown to javascript via [Context.NewError], or the sentinel error of an exceeded resource limit.
+	wrapped error
 }
 
 // prints the error message as a string (to implement the `error` interface).
 func (err *Error) Error() string {
-	message := fmt.Sprintf("[%s]: %s", err.Name, err.Message)
+	message := err.Message
+	if err.Name != "" {
+		message = fmt.Sprintf("[%s]: %s", err.Name, err.Message)
+	}
 	if err.Cause == "" {
 		return message
 	}
 	return fmt.Sprintf("%s (cause: %s)", message, err.Cause)
 }
 
+// free the thrown javascript value held by the error (see [Error.Thrown]), if any.
+// it is safe to call this more than once, and on errors that hold no value.
+func (err *Error) Free() {
+	if err.Thrown == nil {
+		return
+	}
+	if err.Thrown.ctx.ref != nil {
+		err.Thrown.Free()
+	}
+	err.Thrown = nil
+}
+
+// get the original go `error` behind the javascript `Error` (see [Context.NewError]), or `nil` if it did not originate from go.
+// errors thrown by quickjs upon exceeding a resource limit unwrap to [ErrOutOfMemory] or [ErrStackOverflow] instead,
+// while interruptions unwrap to their reason (see [Runtime.Interrupt]).
+//
+// this makes [errors.Is] and [errors.As] see through to go errors that were returned (or panicked) by a [GoFunction],
+// even after they've been thrown through javascript code and back.
+func (err *Error) Unwrap() error {
+	return err.wrapped
+}
+
+// the error thrown to javascript when a [GoFunction] panics, carrying the recovered panic value along with the go stack trace.
+type PanicError struct {
+	Value any    // the value passed to `panic`.
+	Stack string // the go stack trace of the panicking goroutine.
+}
+
+func (err *PanicError) Error() string {
+	return fmt.Sprintf("go panic: %v", err.Value)
+}
+
+// get the panic value if it is an `error`, otherwise `nil`.
+func (err *PanicError) Unwrap() error {
+	wrapped, _ := err.Value.(error)
+	return wrapped
+}
+
+// convert a recovered panic value to a [*PanicError], capturing the current go stack trace.
+//
+// this must be called from the deferred function that recovered the panic, so that the stack trace still includes the panicking frames.
+func newPanicError(recovered any) *PanicError {
+	return &PanicError{Value: recovered, Stack: string(debug.Stack())}
+}
+
 // spawn a new javascript exception value (which is different from an `Error`).
 //
 // note that it does not need to be freed afterwards.
@@ -33,16 +100,48 @@ func (ctx *Context) NewException() *Value {
 }
 
 // create a new javascript error with a given error message.
+// the original go `err` is kept in a read-only property of the javascript error (see the top of this file), so that it can be recovered via [Error.Unwrap].
+// if `err` is a [*PanicError], then the javascript error's `stack` property will hold the go stack trace.
 //
 // you should make sure that `error` is **not** a `nil`!
 //
 // @should-free
 func (ctx *Context) NewError(err error) *Value {
-	val := &Value{ctx: ctx, ref: C.JS_NewError(ctx.ref)}
+	val := ctx.newValue(C.JS_NewError(ctx.ref))
 	val.Set("message", ctx.NewString(err.Error()))
+	if panic_err, ok := err.(*PanicError); ok {
+		val.define("stack", ctx.NewString(panic_err.Stack), C.JS_PROP_CONFIGURABLE|C.JS_PROP_WRITABLE)
+	}
+	// the property is neither enumerable, nor writable, nor configurable, so that scripts cannot replace or remove it.
+	holder := ctx.NewClassInstance(ctx.rt.goErrorClass, err)
+	C.JS_DefinePropertyValue(ctx.ref, val.ref, ctx.goErrorAtom().ref, holder.transfer(), 0)
 	return val
 }
 
+// get the symbol atom under which javascript errors keep their original go `error` (see [Context.NewError]), creating it on first use.
+func (ctx *Context) goErrorAtom() *Atom {
+	if ctx.atomCache.goError == nil {
+		description := ctx.NewString("go.error")
+		defer description.Free()
+		symbol := ctx.NewSymbol(description)
+		defer symbol.Free()
+		ctx.atomCache.goError = symbol.ToAtom()
+		ctx.atomCache.goError.FreeOnExit()
+	}
+	return ctx.atomCache.goError
+}
+
+// get the original go `error` kept in the symbol-keyed property of a javascript error (see [Context.NewError]), or `nil` if there is none.
+func (val *Value) goError() error {
+	holder := val.GetAtom(val.ctx.goErrorAtom())
+	defer holder.Free()
+	if !holder.IsInstanceOfClass(val.ctx.rt.goErrorClass) {
+		return nil
+	}
+	err, _ := holder.Opaque().(error)
+	return err
+}
+
 // if the js-value is an `Error`, a go `error` will be returned (containing its internal message), otherwise you will receive a `nil`.
 func (val *Value) ToError() *Error {
 	if !val.IsError() {
@@ -69,5 +168,36 @@ func (val *Value) ToError() *Error {
 	if !stack.IsUndefined() {
 		err.Stack = stack.ToString()
 	}
+	err.wrapped = val.goError()
+	if err.wrapped == nil {
+		err.wrapped = val.ctx.rt.internalError(err.Name, err.Message)
+	}
 	return err
 }
+
+// convert a thrown (or rejected) javascript value to an [*Error].
+// unlike [Value.ToError], values that are not `Error`s (such as a thrown string) are converted too, by using their string representation as the message,
+// and by handing the value itself over to the caller in the `Thrown` field, if the context captures thrown values (see [Context.SetCaptureThrown]).
+func (val *Value) toThrownError() *Error {
+	if err := val.ToError(); err != nil {
+		return err
+	}
+	if val.IsNull() && val.ctx.rt.limits.takeOutOfMemory() {
+		// when quickjs runs out of memory while creating its "out of memory" error, it throws a `null` instead.
+		return &Error{Name: "InternalError", Message: "out of memory", wrapped: ErrOutOfMemory}
+	}
+	err := &Error{Message: val.ToString()}
+	if val.ctx.captureThrown {
+		// the value belongs to the caller, rather than to whichever scope happens to be active.
+		err.Thrown = val.Dupe().Detach()
+	}
+	return err
+}
+
+// keep the thrown (or rejected) javascript values that are not `Error`s (such as the `42` of a `throw 42`) in the [Error.Thrown] field
+// of the errors returned by this context. this is disabled by default, so that the returned errors can be treated as plain go `error`s.
+//
+// once enabled, the caller owns each captured value, and must release it via [Error.Free] before the context is freed.
+func (ctx *Context) SetCaptureThrown(capture bool) {
+	ctx.captureThrown = capture
+}
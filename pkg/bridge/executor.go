@@ -0,0 +1,225 @@
+// this file contains the executor, which pins a [Runtime] to a single locked os-thread, and marshals calls onto it (see [Runtime.Do]).
+//
+// quickjs runtimes are single-threaded: a runtime (along with its contexts and values) must never be used by two goroutines at once.
+// moreover, quickjs measures its stack usage (see [Runtime.SetMaxStackSize]) relative to the os-thread that created the runtime,
+// which a goroutine may silently migrate away from.
+// a locked runtime avoids both problems, since all of its code runs on one dedicated goroutine that never leaves its os-thread.
+//
+// in debug builds (the `quiccjs_debug` tag), using a locked runtime from any other goroutine panics.
+// a runtime created via [NewRuntime] may be handed from one goroutine to another, but using it while another goroutine
+// is executing code in it (such as during its [Context.Eval]) panics as well.
+// the check is made whenever a value (or atom) is created, freed, transferred, or called, whenever code is evaluated,
+// and by every [Value] and [Atom] method that reads a value, including its type checks, conversions (such as [Value.ToString]),
+// and property accesses (such as [Value.Get]).
+
+package bridge
+
+import (
+	bytes "bytes"
+	errors "errors"
+	fmt "fmt"
+	runtime "runtime"
+	strconv "strconv"
+)
+
+// returned by [Runtime.Do] once the runtime has been freed.
+var ErrRuntimeFreed = errors.New("the runtime has been freed")
+
+// the dedicated goroutine of a locked runtime (see [NewLockedRuntime]).
+type executor struct {
+	calls chan func()
+	// closed once the runtime has been freed, which stops the executor.
+	done    chan struct{}
+	stopped bool
+	// the id of the executor's goroutine, which is the only goroutine allowed to use the runtime.
+	goroutine int64
+	// the context that is passed to the functions of [Runtime.Do].
+	defaultContext *Context
+}
+
+// create a new runtime (along with a default context) that is pinned to a dedicated goroutine, which is locked to its own os-thread.
+// the runtime, its contexts, and its values must only be used inside of [Runtime.Do],
+// with the exception of the methods that are explicitly safe to call from any goroutine (such as [Runtime.Interrupt] and [Runtime.Post]).
+//
+// the dedicated goroutine keeps running until [Runtime.Free] is called, after which its os-thread is terminated.
+// `nil` is returned if the runtime could not be created.
+func NewLockedRuntime() *Runtime {
+	created := make(chan *Runtime)
+	go func() {
+		// the thread is intentionally never unlocked, so that it gets terminated along with the goroutine,
+		// instead of being handed over to other goroutines after quickjs has used it.
+		runtime.LockOSThread()
+		rt := NewRuntime()
+		if rt == nil {
+			created <- nil
+			return
+		}
+		ctx := rt.NewContext()
+		if ctx == nil {
+			rt.Free()
+			created <- nil
+			return
+		}
+		rt.exec = &executor{
+			calls:          make(chan func()),
+			done:           make(chan struct{}),
+			goroutine:      goroutineID(),
+			defaultContext: ctx,
+		}
+		created <- rt
+		rt.exec.loop()
+	}()
+	return <-created
+}
+
+// run `fn` on the runtime's executor with its default context, and wait for it to return.
+// this method is safe to call from any goroutine, and the calls of concurrent goroutines are executed one at a time.
+//
+// calling it from within `fn` itself (or from a [GoFunction] executed by it) runs the nested `fn` immediately, rather than deadlocking.
+// a panic inside of `fn` is re-raised in the calling goroutine as a [*PanicError], which carries the stack trace of the executor.
+// once the runtime has been freed, [ErrRuntimeFreed] is returned without running `fn`.
+//
+// this method panics if the runtime was not created via [NewLockedRuntime].
+//
+// example:
+//
+//	rt := bridge.NewLockedRuntime()
+//	defer rt.Free()
+//	var sum int64
+//	err := rt.Do(func(ctx *bridge.Context) error {
+//		val, err := ctx.Eval(`1 + 2`)
+//		if err != nil {
+//			return err
+//		}
+//		defer val.Free()
+//		sum = val.ToInt64()
+//		return nil
+//	})
+func (rt *Runtime) Do(fn func(ctx *Context) error) error {
+	exec := rt.exec
+	if exec == nil {
+		panic("[Runtime.Do]: the runtime is not pinned to an executor, since it was not created via `NewLockedRuntime`.")
+	}
+	return exec.run(func() error { return fn(exec.defaultContext) })
+}
+
+// execute the calls sent by [executor.run], until the runtime is freed.
+func (exec *executor) loop() {
+	for {
+		select {
+		case call := <-exec.calls:
+			call()
+		case <-exec.done:
+			return
+		}
+	}
+}
+
+// run `fn` on the executor's goroutine and wait for its result (see [Runtime.Do]).
+func (exec *executor) run(fn func() error) error {
+	if exec.isCurrent() {
+		return fn()
+	}
+	result := make(chan error, 1)
+	var panicked *PanicError
+	call := func() {
+		// the executor may have been stopped by an earlier call, while this one was already waiting to be received.
+		if exec.stopped {
+			result <- ErrRuntimeFreed
+			return
+		}
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				panicked = newPanicError(recovered)
+				result <- nil
+			}
+		}()
+		result <- fn()
+	}
+	select {
+	case exec.calls <- call:
+	case <-exec.done:
+		return ErrRuntimeFreed
+	}
+	err := <-result
+	if panicked != nil {
+		panic(panicked)
+	}
+	return err
+}
+
+// stop the executor once the call that freed the runtime returns.
+func (exec *executor) stop() {
+	if !exec.stopped {
+		exec.stopped = true
+		close(exec.done)
+	}
+}
+
+// dictates whether the current goroutine is the executor's goroutine.
+func (exec *executor) isCurrent() bool {
+	return goroutineID() == exec.goroutine
+}
+
+// mark the beginning of an execution of javascript code by the calling goroutine, which must be paired with a deferred [Runtime.exit].
+// executions may be nested (such as when a [GoFunction] evaluates more code), in which case only the outermost one counts.
+//
+// in debug builds, this panics if a runtime created via [NewRuntime] is already executing code on behalf of another goroutine,
+// since quickjs would then be entered by two goroutines at once. handing a runtime over to another goroutine sequentially is fine.
+func (rt *Runtime) enter() {
+	if debugBuild && rt.exec == nil {
+		id := goroutineID()
+		if !rt.executing.CompareAndSwap(0, id) && rt.executing.Load() != id {
+			panic(fmt.Sprintf("[Runtime]: goroutine %d entered a runtime while goroutine %d was executing code in it.", id, rt.executing.Load()))
+		}
+	}
+	if rt.depth == 0 {
+		rt.interrupt.begin()
+	}
+	rt.depth++
+}
+
+// mark the end of an execution that was started via [Runtime.enter].
+func (rt *Runtime) exit() {
+	rt.depth--
+	if rt.depth != 0 {
+		return
+	}
+	rt.interrupt.end()
+	if debugBuild && rt.exec == nil {
+		rt.executing.Store(0)
+	}
+}
+
+// panic if a locked runtime is being used outside of its executor's goroutine,
+// or if a runtime created via [NewRuntime] is being used while another goroutine is executing code in it (see [Runtime.enter]).
+// this is a no-op in regular builds.
+func (rt *Runtime) assertOwner() {
+	if !debugBuild {
+		return
+	}
+	if rt.exec != nil {
+		if !rt.exec.isCurrent() {
+			panic(fmt.Sprintf("[Runtime]: a locked runtime was used by goroutine %d, instead of its executor's goroutine %d (see `Runtime.Do`).", goroutineID(), rt.exec.goroutine))
+		}
+		return
+	}
+	if executing := rt.executing.Load(); executing != 0 {
+		if id := goroutineID(); id != executing {
+			panic(fmt.Sprintf("[Runtime]: a runtime was used by goroutine %d while goroutine %d was executing code in it.", id, executing))
+		}
+	}
+}
+
+// get the id of the current goroutine, which go does not expose directly, so it is parsed from the header of the goroutine's stack trace.
+func goroutineID() int64 {
+	var buf [64]byte
+	n := runtime.Stack(buf[:], false)
+	// the header has the format: "goroutine 123 [running]:".
+	fields := bytes.Fields(buf[:n])
+	if len(fields) < 2 {
+		return 0
+	}
+	id, _ := strconv.ParseInt(string(fields[1]), 10, 64)
+	return id
+}
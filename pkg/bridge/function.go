@@ -3,11 +3,15 @@
 package bridge
 
 /*
-#include "./include0_quickjs.h"
+#include "./include1_helpers.h"
+
+// forward declaration of the go-function trampoline callback, otherwise the compiler won't discover it.
+JSCFunctionData goFunctionTrampoline;
 */
 import "C"
 import (
 	runtime "runtime"
+	cgo "runtime/cgo"
 )
 
 // bind a javascript function to some default arguments.
@@ -28,6 +32,8 @@ func (fun *Value) Bind(this *Value, default_args ...*Value) func(args ...*Value)
 		js_default_args[i] = js_arg.ref
 	}
 	return func(args ...*Value) *Value {
+		ctx.rt.enter()
+		defer ctx.rt.exit()
 		args_len := len(args)
 		js_args := make([]C.JSValue, default_args_len+args_len)
 		copy(js_args, js_default_args) // this operation is fast when the slice is contiguous.
@@ -39,7 +45,7 @@ func (fun *Value) Bind(this *Value, default_args ...*Value) func(args ...*Value)
 			first_js_arg_ptr = &js_args[0]
 		}
 		result_ref := C.JS_Call(ctx.ref, fun.ref, this.ref, C.int(default_args_len), first_js_arg_ptr)
-		return &Value{ctx: ctx, ref: result_ref}
+		return ctx.newValue(result_ref)
 	}
 }
 
@@ -50,6 +56,9 @@ func (fun *Value) Bind(this *Value, default_args ...*Value) func(args ...*Value)
 // in case there is no `this` object that needs to be referenced by your function, simply set the `this` argument to `nil`.
 func (fun *Value) Call(this *Value, args ...*Value) *Value {
 	ctx := fun.ctx
+	ctx.rt.assertOwner()
+	ctx.rt.enter()
+	defer ctx.rt.exit()
 	var this_ref C.JSValue
 	if this == nil {
 		this_ref = C.JS_UNDEFINED
@@ -84,12 +93,15 @@ func (fun *Value) Call(this *Value, args ...*Value) *Value {
 	// so that go does not free them while quick js is using them.
 	runtime.KeepAlive(stack_allocated_args)
 	runtime.KeepAlive(heap_allocated_args)
-	return &Value{ctx: ctx, ref: result_ref}
+	return ctx.newValue(result_ref)
 }
 
 // execute a class's constructor with the given arguments to produce a class instance.
 func (cls *Value) CallConstructor(args ...*Value) *Value {
 	ctx := cls.ctx
+	ctx.rt.assertOwner()
+	ctx.rt.enter()
+	defer ctx.rt.exit()
 
 	// inlined logic of `Context.valuesToCValues()`, because we don't want the returned slice to be allocated on the heap instead of the stack.
 	// a slice "escapes" to the heap when its lifetime extends beyond the function that created it.
@@ -118,5 +130,70 @@ func (cls *Value) CallConstructor(args ...*Value) *Value {
 	// so that go does not free them while quick js is using them.
 	runtime.KeepAlive(stack_allocated_args)
 	runtime.KeepAlive(heap_allocated_args)
-	return &Value{ctx: ctx, ref: result_ref}
+	return ctx.newValue(result_ref)
+}
+
+// the signature of go functions that can be exposed to javascript via [Context.NewFunction].
+//
+//   - the `this` and `args` values are _borrowed_ from quickjs, so you must **not** free them.
+//     if you wish to keep them (or return them), you must [Value.Dupe] them first.
+//   - the returned [Value]'s ownership is transferred to quickjs, so you must **not** free it either.
+//     returning a `nil` value is equivalent to returning `undefined`.
+//   - returning a non-`nil` go `error` will throw it as a javascript `Error` (see [Context.NewError]).
+//   - a panic is recovered, and thrown as a javascript `Error` as well, carrying a [*PanicError] (with the go stack trace).
+type GoFunction = func(ctx *Context, this *Value, args []*Value) (*Value, error)
+
+// the go-data of the hidden "GoFunction" class instance, which gets passed to quickjs as the only element of a go function's `func_data` array.
+type goFunctionData struct {
+	ctx *Context
+	fn  GoFunction
+}
+
+//export goFunctionTrampoline
+func goFunctionTrampoline(ctx_ref *C.JSContext, this_ref C.JSValue, args_len C.int, first_arg_ptr *C.JSValue, magic C.int, func_data_ptr *C.JSValue) C.JSValue {
+	opaque := C.JS_GetOpaque(*func_data_ptr, C.JS_GetClassID(*func_data_ptr))
+	data := cgo.Handle(C.opaqueToHandle(opaque)).Value().(*classInstance).data.(*goFunctionData)
+	return data.ctx.callGoFunction(data.fn, this_ref, args_len, first_arg_ptr)
+}
+
+// call the go-function `fn` on behalf of javascript, and hand its result (or its thrown error) over to quickjs.
+func (ctx *Context) callGoFunction(fn GoFunction, this_ref C.JSValue, args_len C.int, first_arg_ptr *C.JSValue) (result_ref C.JSValue) {
+	defer ctx.suspendScopes()()
+	// a panic must never unwind through quickjs's c-frames, so we convert it into a javascript exception instead.
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			result_ref = C.JS_Throw(ctx.ref, ctx.NewError(newPanicError(recovered)).transfer())
+		}
+	}()
+	this := &Value{ctx: ctx, ref: this_ref}
+	args := ctx.cValuesToValues(args_len, first_arg_ptr)
+	result, err := fn(ctx, this, args)
+	if err != nil {
+		result.Free()
+		return C.JS_Throw(ctx.ref, ctx.NewError(err).transfer())
+	}
+	if result == nil {
+		return C.JS_UNDEFINED
+	}
+	return result.transfer()
+}
+
+// create a new javascript `Function` that calls the go-function `fn` whenever it is invoked from javascript.
+//
+// the `name` and `length` parameters set the `name` and `length` properties of the javascript function.
+// do note that quickjs pads the arguments with `undefined`s up to `length`,
+// so your `fn` will always receive at least `length` number of `args`.
+//
+// under the hood, `fn` is stored inside of an instance of the runtime's hidden "GoFunction" class (see [Runtime.NewClass]),
+// which is passed to `JS_NewCFunctionData` as the function's data. so once quickjs garbage collects the function,
+// the instance's finalizer releases `fn` as well, and functions that are created on the fly do not pile up until the [Context] is freed.
+//
+// @should-free
+func (ctx *Context) NewFunction(name string, length int, fn GoFunction) *Value {
+	data := ctx.NewClassInstance(ctx.rt.goFunctionClass, &goFunctionData{ctx: ctx, fn: fn})
+	defer data.Free()
+	fun := ctx.newValue(C.JS_NewCFunctionData(ctx.ref, &C.goFunctionTrampoline, C.int(length), 0, 1, &data.ref))
+	// functions created via `JS_NewCFunctionData` are nameless, so we must define their non-writable `name` property ourselves.
+	fun.define("name", ctx.NewString(name), C.JS_PROP_CONFIGURABLE)
+	return fun
 }
@@ -0,0 +1,20 @@
+#pragma once
+#include "./include0_quickjs.h"
+#include <stdint.h>
+
+// cgo handles are plain integers, but quickjs stores "opaque" user data as `void*` pointers.
+// casting between the two in go (via `unsafe.Pointer`) upsets `go vet`, so we perform the casts over here on the c-side instead.
+static inline void* handleToOpaque(uintptr_t handle) { return (void*)handle; }
+static inline uintptr_t opaqueToHandle(void* opaque) { return (uintptr_t)opaque; }
+
+// a typedef for referring to `const char*` parameters of callbacks from go (see the explanation in "./print.go").
+typedef const char const_char_t;
+
+// get the `JSModuleDef*` pointer hidden inside of a compiled module's `JSValue` (since cgo can't call function-like macros).
+static inline JSModuleDef* valueToModuleDef(JSValue module_val) { return (JSModuleDef*)JS_VALUE_GET_PTR(module_val); }
+
+// get the pointer of a reference-counted `JSValue` (such as an object), which serves as its identity.
+static inline void* valueToPtr(JSValue val) { return JS_VALUE_GET_PTR(val); }
+
+// check if a `JSValue` is reference-counted (such as objects and strings), as opposed to being a plain primitive (such as numbers and booleans).
+static inline int valueHasRefCount(JSValue val) { return JS_VALUE_HAS_REF_COUNT(val); }
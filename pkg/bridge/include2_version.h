@@ -0,0 +1,7 @@
+#pragma once
+
+// the version of the vendored quickjs library, which gets compiled into quickjs (see "./compile4_quickjs.c"),
+// and which go reads as the `QuickjsVersion` constant (see "./bytecode.go"), so that the two cannot drift apart.
+// it must match the "VERSION" file of the vendored quickjs sources (which the tests of "./bytecode.go" check),
+// and the precompiled libraries linked by debug builds (see "./cgo.go") must be built from those very sources.
+#define CONFIG_VERSION "2025-09-13"
@@ -0,0 +1,223 @@
+// this file contains the interruption of running javascript code, based on quickjs's interrupt handler,
+// which quickjs polls periodically (roughly every ten thousand function calls or loop iterations) while it executes code.
+//
+// javascript code can be interrupted in three ways:
+//   - by binding its execution to a go [context.Context] (see [Context.EvalContext], [Value.CallContext], and [Runtime.RunLoop]),
+//     in which case it gets interrupted once the context is cancelled or its deadline is exceeded.
+//   - by calling [Runtime.Interrupt] from any goroutine.
+//   - by exhausting the instruction budget of the runtime (see [Runtime.SetInstructionBudget]).
+//
+// the interruption throws an uncatchable `InternalError`, so scripts cannot swallow it with a `try`/`catch` statement.
+// the resulting [*Error] unwraps to the reason of the interruption ([context.DeadlineExceeded], [ErrInterrupted], etc...).
+
+package bridge
+
+/*
+#include "./include1_helpers.h"
+
+// forward declaration of the interrupt handler callback function, otherwise the compiler won't discover it.
+JSInterruptHandler goInterruptHandler;
+
+// throw the same uncatchable error that quickjs throws when its interrupt handler returns `1` (cgo cannot call variadic functions).
+static inline JSValue throwInterrupted(JSContext *ctx) {
+	JS_ThrowInternalError(ctx, "interrupted");
+	JS_SetUncatchableException(ctx, 1);
+	return JS_EXCEPTION;
+}
+*/
+import "C"
+import (
+	context "context"
+	errors "errors"
+	cgo "runtime/cgo"
+	sync "sync"
+	sync_atomic "sync/atomic"
+	unsafe "unsafe"
+)
+
+var (
+	// returned (wrapped inside an [*Error]) when javascript code has been interrupted via [Runtime.Interrupt].
+	ErrInterrupted = errors.New("interrupted")
+	// returned (wrapped inside an [*Error]) when javascript code has exhausted the instruction budget of its runtime (see [Runtime.SetInstructionBudget]).
+	ErrInstructionBudget = errors.New("instruction budget exhausted")
+)
+
+// the state of a runtime's interrupt handler.
+type interruptState struct {
+	// set by [Runtime.Interrupt], which may be called from any goroutine, and cleared once the outermost execution ends.
+	requested sync_atomic.Bool
+	// dictates whether javascript code is being executed (see [Runtime.enter]), guarded by the mutex.
+	// [Runtime.Interrupt] only takes effect while this is `true`.
+	running bool
+	// the go contexts that the currently running javascript code is bound to (see [Runtime.bindContext]).
+	goctxs []context.Context
+	// the number of remaining interrupt checks before the code is interrupted, or `-1` if there is no budget.
+	budget int
+	// the reason behind the latest interruption, which is consumed once the thrown exception is converted to an [*Error].
+	reason error
+	// called by [Runtime.Interrupt] while go code is blocking on behalf of javascript (see [interruptState.watch]), guarded by the mutex.
+	mutex       sync.Mutex
+	onInterrupt func()
+}
+
+func (rt *Runtime) initInterruptHandler() {
+	rt.interrupt = &interruptState{budget: -1}
+	C.JS_SetInterruptHandler(rt.ref, &C.goInterruptHandler, C.handleToOpaque(C.uintptr_t(rt.handle)))
+}
+
+//export goInterruptHandler
+func goInterruptHandler(rt_ref *C.JSRuntime, opaque unsafe.Pointer) C.int {
+	rt := cgo.Handle(C.opaqueToHandle(opaque)).Value().(*Runtime)
+	reason := rt.interrupt.check()
+	if reason == nil {
+		return 0
+	}
+	rt.interrupt.reason = reason
+	return 1
+}
+
+// get the reason for interrupting the running code, or `nil` if it should continue.
+func (state *interruptState) check() error {
+	if state.requested.Swap(false) {
+		return ErrInterrupted
+	}
+	for _, goctx := range state.goctxs {
+		if err := goctx.Err(); err != nil {
+			return err
+		}
+	}
+	if state.budget == 0 {
+		return ErrInstructionBudget
+	}
+	if state.budget > 0 {
+		state.budget--
+	}
+	return nil
+}
+
+// get a channel that is closed once the running code should be interrupted (via [Runtime.Interrupt], or by one of its bound go contexts),
+// along with the function that stops watching. this lets go code that blocks on behalf of javascript (such as `Atomics.wait`) wake up,
+// since quickjs cannot poll the interrupt handler in the meantime.
+func (state *interruptState) watch() (interrupted <-chan struct{}, stop func()) {
+	ch := make(chan struct{})
+	var once sync.Once
+	fire := func() { once.Do(func() { close(ch) }) }
+	state.mutex.Lock()
+	state.onInterrupt = fire
+	state.mutex.Unlock()
+	if state.requested.Load() {
+		fire()
+	}
+	stops := make([]func() bool, 0, len(state.goctxs))
+	for _, goctx := range state.goctxs {
+		stops = append(stops, context.AfterFunc(goctx, fire))
+	}
+	return ch, func() {
+		state.mutex.Lock()
+		state.onInterrupt = nil
+		state.mutex.Unlock()
+		for _, stop := range stops {
+			stop()
+		}
+	}
+}
+
+// interrupt the running javascript code from within a [GoFunction] that noticed the interruption while it was blocking
+// (see [interruptState.watch]), by throwing the same uncatchable error as quickjs does. the returned exception must be returned by the function.
+func (ctx *Context) throwInterrupted() *Value {
+	state := ctx.rt.interrupt
+	state.reason = state.check()
+	if state.reason == nil {
+		// the request that woke the function up has already been consumed, but the interruption is genuine nonetheless.
+		state.reason = ErrInterrupted
+	}
+	return &Value{ctx: ctx, ref: C.throwInterrupted(ctx.ref)}
+}
+
+// take the reason behind the latest interruption (see [Runtime.internalError]),
+// or `nil` if the runtime has not been interrupted (in which case the "interrupted" error was not thrown by us).
+func (state *interruptState) takeReason() error {
+	reason := state.reason
+	state.reason = nil
+	return reason
+}
+
+// mark the beginning of the outermost execution of javascript code (see [Runtime.enter]).
+func (state *interruptState) begin() {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	state.running = true
+}
+
+// mark the end of the outermost execution, and drop any interruption request that it did not consume,
+// so that it cannot leak into the next (unrelated) execution.
+func (state *interruptState) end() {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	state.running = false
+	state.requested.Store(false)
+}
+
+// interrupt the javascript code that is currently running in the runtime, which will then throw an error that unwraps to [ErrInterrupted].
+//
+// this method is safe to call from any goroutine.
+// it only affects the execution that is in progress (such as a [Context.Eval], a [Value.Call], or a [Runtime.RunLoop]).
+// if the runtime is idle, then the request is ignored, and if the execution finishes before quickjs polls the interrupt handler,
+// then the request is dropped along with it. so the next execution is never affected by an earlier call.
+func (rt *Runtime) Interrupt() {
+	state := rt.interrupt
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	if !state.running {
+		return
+	}
+	state.requested.Store(true)
+	if state.onInterrupt != nil {
+		state.onInterrupt()
+	}
+}
+
+// limit the number of times that quickjs may poll the interrupt handler before the running code gets interrupted,
+// with an error that unwraps to [ErrInstructionBudget]. a negative `budget` removes the limit (which is the default).
+//
+// quickjs polls the handler roughly every ten thousand function calls or loop iterations,
+// so unlike a deadline, the budget is deterministic for a given script, which makes it suitable for tests.
+// the budget is shared by all executions, and it is not replenished until this method is called again.
+func (rt *Runtime) SetInstructionBudget(budget int) {
+	if budget < 0 {
+		budget = -1
+	}
+	rt.interrupt.budget = budget
+}
+
+// bind the javascript code executed by the runtime to the go context `goctx`, until the returned `unbind` function is called.
+// bindings can be nested (such as when awaiting a promise inside of [Context.EvalContext]), in which case all of them remain in effect.
+func (rt *Runtime) bindContext(goctx context.Context) (unbind func()) {
+	state := rt.interrupt
+	state.goctxs = append(state.goctxs, goctx)
+	return func() { state.goctxs = state.goctxs[:len(state.goctxs)-1] }
+}
+
+// same as [Context.Eval], but the code gets interrupted once `goctx` is cancelled or its deadline is exceeded,
+// in which case the returned [*Error] unwraps to the context's error (such as [context.DeadlineExceeded]).
+//
+// @should-free
+func (ctx *Context) EvalContext(goctx context.Context, code string) (*Value, error) {
+	if err := goctx.Err(); err != nil {
+		return nil, err
+	}
+	defer ctx.rt.bindContext(goctx)()
+	return ctx.Eval(code)
+}
+
+// same as [Value.TryCall], but the function gets interrupted once `goctx` is cancelled or its deadline is exceeded,
+// in which case the returned [*Error] unwraps to the context's error (such as [context.DeadlineExceeded]).
+//
+// @should-free
+func (fun *Value) CallContext(goctx context.Context, this *Value, args ...*Value) (*Value, error) {
+	if err := goctx.Err(); err != nil {
+		return nil, err
+	}
+	defer fun.ctx.rt.bindContext(goctx)()
+	return fun.TryCall(this, args...)
+}
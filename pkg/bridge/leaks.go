@@ -0,0 +1,124 @@
+// this file contains the leak detector of debug builds, which tracks every [Value] and [Atom] owned by go,
+// along with the go stack trace of where it was created.
+// when a [Context] is freed, the detector reports the handles that were never freed,
+// along with the handles that were freed (or had their ownership transferred) without being owned (such as double frees, and freed borrowed values).
+//
+// the detector is only compiled in when building with the `quiccjs_debug` tag (for instance: `go test -tags="quiccjs_debug" ./...`),
+// since capturing a stack trace for every handle is rather slow. in all other builds, it costs nothing.
+//
+// only reference-counted values (such as objects and strings) are tracked, since freeing primitives (such as numbers) is a no-op anyway.
+
+package bridge
+
+/*
+#include "./include1_helpers.h"
+*/
+import "C"
+import (
+	fmt "fmt"
+	os "os"
+)
+
+// a mishandled [Value] or [Atom], as reported by the leak detector of debug builds (see [Context.OnLeak]).
+type Leak struct {
+	Kind    string // the kind of the handle, either "Value" or "Atom".
+	Problem string // a description of the problem, such as "never freed".
+	Stack   string // the go stack trace of where the handle was created (for unfreed handles), or where it was mishandled.
+}
+
+func (leak Leak) String() string {
+	return fmt.Sprintf("%s %s, at:\n%s", leak.Kind, leak.Problem, leak.Stack)
+}
+
+// set the function that receives the leaks detected when the context is freed (see [Context.Free]).
+// by default, the leaks are written to the standard error output.
+//
+// the function is only ever called in debug builds (see [LeakDetection]), and only when there is at least one leak.
+// for tests, see `bridgetest.CheckLeaks`, which reports the leaks as test errors.
+func (ctx *Context) OnLeak(fn func(leaks []Leak)) {
+	ctx.leaks.reporter = fn
+}
+
+// report the leaked handles of a context that is about to be freed (see [Context.OnLeak]).
+func (ctx *Context) reportLeaks() {
+	if !LeakDetection {
+		return
+	}
+	leaks := ctx.leaks.collect()
+	if len(leaks) == 0 {
+		return
+	}
+	if ctx.leaks.reporter != nil {
+		ctx.leaks.reporter(leaks)
+		return
+	}
+	for _, leak := range leaks {
+		fmt.Fprintf(os.Stderr, "[quiccjs leak]: %s\n", leak)
+	}
+}
+
+// create a go-owned [Value] wrapper of `ref`, which must later be freed (or have its ownership transferred).
+// in debug builds, the value gets tracked by the leak detector. if a [Scope] is active, then the value gets recorded by it as well.
+//
+// for borrowed values (such as the arguments of a [GoFunction]), create the wrapper directly instead.
+func (ctx *Context) newValue(ref C.JSValue) *Value {
+	ctx.rt.assertOwner()
+	val := &Value{ctx: ctx, ref: ref}
+	if LeakDetection && C.valueHasRefCount(ref) != 0 {
+		ctx.leaks.trackValue(val)
+	}
+	if ctx.scope != nil {
+		ctx.scope.addValue(val)
+	}
+	return val
+}
+
+// create a go-owned [Atom] wrapper of `ref`, which must later be freed.
+// in debug builds, the atom gets tracked by the leak detector. if a [Scope] is active, then the atom gets recorded by it as well.
+func (ctx *Context) newAtom(ref C.JSAtom) *Atom {
+	ctx.rt.assertOwner()
+	atom := &Atom{ctx: ctx, ref: ref}
+	if LeakDetection {
+		ctx.leaks.trackAtom(atom)
+	}
+	if ctx.scope != nil {
+		ctx.scope.addAtom(atom)
+	}
+	return atom
+}
+
+// get the c-context of the value, for passing it to a quickjs function,
+// after asserting that the calling goroutine is allowed to use the value's runtime (see [Runtime.assertOwner]).
+func (val *Value) cctx() *C.JSContext {
+	val.ctx.rt.assertOwner()
+	return val.ctx.ref
+}
+
+// same as [Value.cctx], but for atoms.
+func (atom *Atom) cctx() *C.JSContext {
+	atom.ctx.rt.assertOwner()
+	return atom.ctx.ref
+}
+
+// check that the value is not `nil`, after asserting that the calling goroutine is allowed to use the value's runtime (see [Runtime.assertOwner]).
+// this is meant for the type checks that merely inspect the value's tag, without calling into quickjs with its context.
+func (val *Value) owned() bool {
+	if val == nil {
+		return false
+	}
+	if debugBuild && val.ctx != nil {
+		val.ctx.rt.assertOwner()
+	}
+	return true
+}
+
+// mark the value's ownership as transferred to quickjs (which will free it on our behalf), and return its c-value.
+// this must be used whenever a value is passed to a quickjs function that consumes it (such as `JS_SetPropertyStr`).
+func (val *Value) transfer() C.JSValue {
+	val.ctx.rt.assertOwner()
+	if LeakDetection {
+		val.ctx.leaks.releaseValue(val, "transferred")
+	}
+	val.scope = nil
+	return val.ref
+}
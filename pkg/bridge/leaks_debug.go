@@ -0,0 +1,103 @@
+//go:build quiccjs_debug
+
+// this file contains the leak tracker of debug builds (see `./leaks.go`), along with the debug build flag.
+
+package bridge
+
+/*
+#include "./include1_helpers.h"
+*/
+import "C"
+import (
+	debug "runtime/debug"
+	slices "slices"
+	strings "strings"
+	sync "sync"
+)
+
+// whether this is a debug build (the `quiccjs_debug` tag), which enables the runtime ownership assertion (see [Runtime.Do]).
+const debugBuild = true
+
+// whether the leak detector is compiled in, which is only the case when building with the `quiccjs_debug` tag (see [Context.OnLeak]).
+const LeakDetection = debugBuild
+
+// the handles owned by go, along with the go stack traces of their creation.
+type leakTracker struct {
+	// guards the maps, so that the tracker itself stays consistent even when a runtime is misused by several goroutines at once.
+	mutex    sync.Mutex
+	values   map[*Value]string
+	atoms    map[*Atom]string
+	misuses  []Leak
+	reporter func(leaks []Leak)
+}
+
+func (tracker *leakTracker) trackValue(val *Value) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	if tracker.values == nil {
+		tracker.values = map[*Value]string{}
+	}
+	tracker.values[val] = string(debug.Stack())
+}
+
+func (tracker *leakTracker) trackAtom(atom *Atom) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	if tracker.atoms == nil {
+		tracker.atoms = map[*Atom]string{}
+	}
+	tracker.atoms[atom] = string(debug.Stack())
+}
+
+// stop tracking a value that has been freed or transferred (the `action`), or record a misuse if the value was not owned to begin with.
+func (tracker *leakTracker) releaseValue(val *Value, action string) {
+	if C.valueHasRefCount(val.ref) == 0 {
+		return
+	}
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	if _, ok := tracker.values[val]; ok {
+		delete(tracker.values, val)
+		return
+	}
+	tracker.misuses = append(tracker.misuses, Leak{
+		Kind:    "Value",
+		Problem: action + " without being owned (it was either already freed, or borrowed)",
+		Stack:   string(debug.Stack()),
+	})
+}
+
+// stop tracking an atom that has been freed (the `action`), or record a misuse if the atom was not owned to begin with.
+func (tracker *leakTracker) releaseAtom(atom *Atom, action string) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	if _, ok := tracker.atoms[atom]; ok {
+		delete(tracker.atoms, atom)
+		return
+	}
+	tracker.misuses = append(tracker.misuses, Leak{
+		Kind:    "Atom",
+		Problem: action + " without being owned (it was either already freed, or borrowed)",
+		Stack:   string(debug.Stack()),
+	})
+}
+
+// take all misuses, and all the handles that are still being tracked (i.e. the unfreed ones), thereby resetting the tracker.
+func (tracker *leakTracker) collect() []Leak {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	leaks := tracker.misuses
+	unfreed := make([]Leak, 0, len(tracker.values)+len(tracker.atoms))
+	for _, stack := range tracker.values {
+		unfreed = append(unfreed, Leak{Kind: "Value", Problem: "never freed", Stack: stack})
+	}
+	for _, stack := range tracker.atoms {
+		unfreed = append(unfreed, Leak{Kind: "Atom", Problem: "never freed", Stack: stack})
+	}
+	// we sort the unfreed handles so that the order of the report remains deterministic.
+	slices.SortFunc(unfreed, func(a, b Leak) int {
+		return strings.Compare(a.Kind+a.Stack, b.Kind+b.Stack)
+	})
+	tracker.values, tracker.atoms, tracker.misuses = nil, nil, nil
+	return append(leaks, unfreed...)
+}
@@ -0,0 +1,21 @@
+//go:build !quiccjs_debug
+
+// this file contains the no-op leak tracker of regular builds (see `./leaks.go`), along with the debug build flag.
+
+package bridge
+
+// whether this is a debug build (the `quiccjs_debug` tag), which enables the runtime ownership assertion (see [Runtime.Do]).
+const debugBuild = false
+
+// whether the leak detector is compiled in, which is only the case when building with the `quiccjs_debug` tag (see [Context.OnLeak]).
+const LeakDetection = debugBuild
+
+type leakTracker struct {
+	reporter func(leaks []Leak)
+}
+
+func (tracker *leakTracker) trackValue(val *Value)                  {}
+func (tracker *leakTracker) trackAtom(atom *Atom)                   {}
+func (tracker *leakTracker) releaseValue(val *Value, action string) {}
+func (tracker *leakTracker) releaseAtom(atom *Atom, action string)  {}
+func (tracker *leakTracker) collect() []Leak                        { return nil }
@@ -0,0 +1,191 @@
+// this file contains the resource limits of a [Runtime] (its memory cap, its maximum stack size, and its garbage collection threshold),
+// along with the sentinel errors that exceeding those limits produces.
+//
+// quickjs reports both conditions by throwing an `InternalError` (with the messages "out of memory" and "stack overflow"),
+// which [Value.ToError] recognizes, so that the resulting [*Error] unwraps to [ErrOutOfMemory] or [ErrStackOverflow].
+// interruptions (see `./interrupt.go`) are reported in the same way.
+//
+// since scripts can throw the very same errors themselves (`throw new InternalError("out of memory")`),
+// the message alone is not trusted for running out of memory. instead, every runtime allocates its memory through our own allocator
+// (see `limitMalloc` below), which records the allocations that failed, and only an error backed by such a record unwraps to [ErrOutOfMemory].
+// the same goes for interruptions, whose reason is recorded by the interrupt handler.
+//
+// stack overflows, on the other hand, are recognized by their name and message alone, since quickjs exposes no trace of them to embedders.
+// so a script that throws `new InternalError("stack overflow")` by itself produces an error that unwraps to [ErrStackOverflow] as well.
+
+package bridge
+
+/*
+#include "./include0_quickjs.h"
+#include <stddef.h>
+#include <stdlib.h>
+
+// the runtime-side record of the resource limits that quickjs has run into, which is kept up to date by our allocator.
+typedef struct {
+	// the number of allocations that failed, either due to the memory limit, or due to the system running out of memory.
+	size_t out_of_memory;
+} limitState;
+
+// every allocation is prefixed by a header holding its size, so that it can be accounted for without relying on platform-specific functions.
+typedef union {
+	size_t size;
+	max_align_t align;
+} allocHeader;
+
+// check if growing the allocated memory by `size` bytes would exceed the memory limit (where a limit of `0` means that there is none).
+static inline int limitExceeded(JSMallocState *s, size_t size) {
+	return s->malloc_limit != 0 && s->malloc_size + size > s->malloc_limit;
+}
+
+static void *limitMalloc(JSMallocState *s, size_t size) {
+	limitState *state = s->opaque;
+	allocHeader *header = NULL;
+	if (!limitExceeded(s, sizeof(allocHeader) + size)) header = malloc(sizeof(allocHeader) + size);
+	if (header == NULL) {
+		state->out_of_memory++;
+		return NULL;
+	}
+	header->size = size;
+	s->malloc_count++;
+	s->malloc_size += sizeof(allocHeader) + size;
+	return header + 1;
+}
+
+static void limitFree(JSMallocState *s, void *ptr) {
+	if (ptr == NULL) return;
+	allocHeader *header = (allocHeader*)ptr - 1;
+	s->malloc_count--;
+	s->malloc_size -= sizeof(allocHeader) + header->size;
+	free(header);
+}
+
+static void *limitRealloc(JSMallocState *s, void *ptr, size_t size) {
+	if (ptr == NULL) return limitMalloc(s, size);
+	if (size == 0) {
+		limitFree(s, ptr);
+		return NULL;
+	}
+	limitState *state = s->opaque;
+	allocHeader *header = (allocHeader*)ptr - 1;
+	size_t old_size = header->size;
+	if (size > old_size && limitExceeded(s, size - old_size)) header = NULL;
+	else header = realloc(header, sizeof(allocHeader) + size);
+	if (header == NULL) {
+		state->out_of_memory++;
+		return NULL;
+	}
+	header->size = size;
+	s->malloc_size = s->malloc_size - old_size + size;
+	return header + 1;
+}
+
+static size_t limitUsableSize(const void *ptr) {
+	return ptr == NULL ? 0 : ((const allocHeader*)ptr - 1)->size;
+}
+
+static const JSMallocFunctions limitMallocFunctions = {limitMalloc, limitFree, limitRealloc, limitUsableSize};
+
+// create a runtime that allocates its memory through our allocator, which keeps `state` up to date.
+static inline JSRuntime *newLimitedRuntime(limitState *state) {
+	return JS_NewRuntime2(&limitMallocFunctions, state);
+}
+*/
+import "C"
+import (
+	errors "errors"
+	unsafe "unsafe"
+)
+
+var (
+	// returned (wrapped inside an [*Error]) when javascript code exceeds the memory limit of its runtime (see [Runtime.SetMemoryLimit]).
+	ErrOutOfMemory = errors.New("out of memory")
+	// returned (wrapped inside an [*Error]) when javascript code exceeds the maximum stack size of its runtime (see [Runtime.SetMaxStackSize]).
+	ErrStackOverflow = errors.New("stack overflow")
+)
+
+// set the maximum number of bytes that the runtime may allocate, or `0` to remove the limit (which is the default).
+//
+// once the limit is reached, the javascript code that attempted the allocation throws an error that unwraps to [ErrOutOfMemory].
+// the limit should be generous enough to leave room for the builtins of every context, which are allocated when a context is created.
+func (rt *Runtime) SetMemoryLimit(limit int) {
+	C.JS_SetMemoryLimit(rt.ref, C.size_t(limit))
+}
+
+// set the maximum number of bytes that the runtime's c-stack may grow to, or `0` to disable the check altogether.
+// the default is quickjs's default of 1MiB.
+//
+// once the limit is reached, the javascript code throws an error that unwraps to [ErrStackOverflow].
+func (rt *Runtime) SetMaxStackSize(size int) {
+	C.JS_SetMaxStackSize(rt.ref, C.size_t(size))
+}
+
+// set the number of allocated bytes after which the runtime triggers a garbage collection cycle.
+func (rt *Runtime) SetGCThreshold(threshold int) {
+	C.JS_SetGCThreshold(rt.ref, C.size_t(threshold))
+}
+
+// force a garbage collection cycle, which frees up the unreachable cyclic objects of the runtime.
+func (rt *Runtime) RunGC() {
+	C.JS_RunGC(rt.ref)
+}
+
+// the runtime-side record of the resource limits that a runtime has run into (see the top of this file),
+// along with the counts of the records that have already been matched with an error (see [Runtime.internalError]).
+type limitRecord struct {
+	state           *C.limitState
+	seenOutOfMemory C.size_t
+}
+
+// create a quickjs runtime whose memory is allocated through our allocator, along with its (c-allocated) record.
+// returns a `nil` runtime if quickjs fails to create it.
+func newLimitedRuntime() (*C.JSRuntime, *limitRecord) {
+	state := (*C.limitState)(C.calloc(1, C.sizeof_limitState))
+	ref := C.newLimitedRuntime(state)
+	if ref == nil {
+		C.free(unsafe.Pointer(state))
+		return nil, nil
+	}
+	return ref, &limitRecord{state: state}
+}
+
+// free the record, which must be done after the runtime itself has been freed, since quickjs updates it until its very last deallocation.
+func (record *limitRecord) free() {
+	C.free(unsafe.Pointer(record.state))
+	record.state = nil
+}
+
+// check if an allocation has failed since the last call, which marks the failure as seen.
+func (record *limitRecord) takeOutOfMemory() bool {
+	count := record.state.out_of_memory
+	seen := count != record.seenOutOfMemory
+	record.seenOutOfMemory = count
+	return seen
+}
+
+// get the number of allocations of the runtime that have failed so far.
+// comparing it before and after running some code tells whether the runtime ran out of memory in the meantime, even if the error was swallowed.
+func (record *limitRecord) outOfMemoryCount() int {
+	return int(record.state.out_of_memory)
+}
+
+// get the go error behind the `InternalError`s that quickjs throws when a resource limit is exceeded (or when the code is interrupted),
+// or `nil` if it is not one of them.
+// errors with the same name and message that were not raised by the engine (such as those thrown by scripts) are not recognized,
+// since the runtime holds no record of hitting the limit (or of being interrupted) for them. the exception is the stack overflow,
+// which quickjs leaves no record of (see the top of this file).
+func (rt *Runtime) internalError(name string, message string) error {
+	if name != "InternalError" {
+		return nil
+	}
+	switch message {
+	case "out of memory":
+		if rt.limits.takeOutOfMemory() {
+			return ErrOutOfMemory
+		}
+	case "stack overflow":
+		return ErrStackOverflow
+	case "interrupted":
+		return rt.interrupt.takeReason()
+	}
+	return nil
+}
@@ -0,0 +1,292 @@
+// this file contains the event loop of a [Runtime], which drives asynchronous javascript code to completion.
+//
+// each iteration of the loop performs the following steps:
+//  1. execute all pending jobs (i.e. the microtasks, such as promise reactions and the continuations of `await`).
+//  2. report the first promise rejection that remained unhandled after the microtasks were drained.
+//  3. execute the callback of the earliest timer whose deadline has been reached (see `./timers.go`).
+//  4. otherwise, execute the next macrotask posted from go (such as the completion of an IO operation running in a goroutine).
+//  5. if there is nothing to execute, then wait for a macrotask to be posted or for the next timer to become due,
+//     unless there are neither timers nor reservations (see [Runtime.Reserve]), in which case the loop exits.
+
+package bridge
+
+/*
+#include "./include1_helpers.h"
+
+// forward declaration of the promise rejection tracking callback function, otherwise the compiler won't discover it.
+JSHostPromiseRejectionTracker goPromiseRejectionTracker;
+*/
+import "C"
+import (
+	context "context"
+	errors "errors"
+	fmt "fmt"
+	slices "slices"
+	sync "sync"
+	time "time"
+	unsafe "unsafe"
+)
+
+// a macrotask that is executed by the event loop on the runtime's thread.
+// a non-`nil` returned error stops the event loop, and gets returned by [Runtime.RunLoop].
+type Task = func() error
+
+// the state of a runtime's event loop.
+type eventLoop struct {
+	// guards the `tasks` queue and the `reserved` count, since they're modified from other goroutines.
+	mutex    sync.Mutex
+	tasks    []Task
+	reserved int
+	// signals the waiting event loop that a task has been posted (or that a reservation has been released).
+	wake chan struct{}
+	// the rejected promises that currently lack a rejection handler, in the order that they were rejected.
+	unhandledRejections []*Value
+	// the timers created by `setTimeout` and `setInterval` (see [Context.RegisterTimers]).
+	timers *timerQueue
+}
+
+func (rt *Runtime) initEventLoop() {
+	rt.loop = &eventLoop{wake: make(chan struct{}, 1), timers: newTimerQueue()}
+	C.JS_SetHostPromiseRejectionTracker(rt.ref, &C.goPromiseRejectionTracker, nil)
+}
+
+//export goPromiseRejectionTracker
+func goPromiseRejectionTracker(ctx_ref *C.JSContext, promise C.JSValue, reason C.JSValue, is_handled C.JS_BOOL, opaque unsafe.Pointer) {
+	ctx := contextFromRef(ctx_ref)
+	loop := ctx.rt.loop
+	if is_handled == 0 {
+		loop.unhandledRejections = append(loop.unhandledRejections, ctx.newValue(C.JS_DupValue(ctx.ref, promise)).Detach())
+		return
+	}
+	// a handler was attached to a promise that had been rejected earlier, so it is no longer unhandled.
+	loop.forgetRejection(&Value{ctx: ctx, ref: promise})
+}
+
+// post a macrotask to the runtime's event loop.
+//
+// this method is safe to call from any goroutine, and it is the only way for goroutines to interact with javascript,
+// since the `task` will be executed on the thread that runs the event loop (see [Runtime.RunLoop]).
+func (rt *Runtime) Post(task Task) {
+	loop := rt.loop
+	loop.mutex.Lock()
+	loop.tasks = append(loop.tasks, task)
+	loop.mutex.Unlock()
+	loop.signal()
+}
+
+// reserve a macrotask that will be posted in the future, so that the event loop keeps waiting for it instead of exiting.
+// the returned `complete` function posts the task and releases the reservation, and it must be called exactly once.
+//
+// this is intended for asynchronous go-work. for example:
+//
+//	promise, resolve, reject := ctx.NewPromise()
+//	complete := rt.Reserve()
+//	go func() {
+//		data, err := os.ReadFile(path)
+//		complete(func() error {
+//			if err != nil {
+//				reject(err)
+//			} else {
+//				resolve(ctx.NewString(string(data)))
+//			}
+//			return nil
+//		})
+//	}()
+//
+// this method, along with the returned `complete` function, is safe to call from any goroutine.
+func (rt *Runtime) Reserve() (complete func(task Task)) {
+	loop := rt.loop
+	loop.mutex.Lock()
+	loop.reserved++
+	loop.mutex.Unlock()
+	var once sync.Once
+	return func(task Task) {
+		once.Do(func() {
+			loop.mutex.Lock()
+			loop.reserved--
+			if task != nil {
+				loop.tasks = append(loop.tasks, task)
+			}
+			loop.mutex.Unlock()
+			loop.signal()
+		})
+	}
+}
+
+func (loop *eventLoop) signal() {
+	select {
+	case loop.wake <- struct{}{}:
+	default:
+		// the loop has already been signaled, and it hasn't woken up yet.
+	}
+}
+
+// pop the next macrotask, and also report whether the loop has any remaining work (i.e. queued tasks or reservations).
+func (loop *eventLoop) popTask() (task Task, has_work bool) {
+	loop.mutex.Lock()
+	defer loop.mutex.Unlock()
+	if len(loop.tasks) > 0 {
+		task = loop.tasks[0]
+		loop.tasks[0] = nil
+		loop.tasks = loop.tasks[1:]
+	}
+	return task, task != nil || len(loop.tasks) > 0 || loop.reserved > 0
+}
+
+// take the oldest unhandled promise rejection as a go `error`, or return `nil` if there is none.
+func (loop *eventLoop) takeUnhandledRejection() error {
+	if len(loop.unhandledRejections) == 0 {
+		return nil
+	}
+	promise := loop.unhandledRejections[0]
+	loop.unhandledRejections = loop.unhandledRejections[1:]
+	defer promise.Free()
+	reason := promise.PromiseResult()
+	defer reason.Free()
+	return fmt.Errorf("unhandled promise rejection: %w", reason.toThrownError())
+}
+
+// stop tracking the given promise as an unhandled rejection.
+func (loop *eventLoop) forgetRejection(promise *Value) {
+	promise_ptr := C.valueToPtr(promise.ref)
+	loop.unhandledRejections = slices.DeleteFunc(loop.unhandledRejections, func(rejected *Value) bool {
+		if C.valueToPtr(rejected.ref) != promise_ptr {
+			return false
+		}
+		rejected.Free()
+		return true
+	})
+}
+
+// release the unhandled rejections and the timers belonging to a context that is about to be freed.
+func (loop *eventLoop) forgetContext(ctx *Context) {
+	loop.timers.forgetContext(ctx)
+	loop.unhandledRejections = slices.DeleteFunc(loop.unhandledRejections, func(rejected *Value) bool {
+		if rejected.ctx != ctx {
+			return false
+		}
+		rejected.Free()
+		return true
+	})
+}
+
+// execute all pending jobs (microtasks) of the runtime, until none remain.
+// if a job throws, the execution stops, and the thrown exception is returned as an error.
+func (rt *Runtime) ExecutePendingJobs() error {
+	rt.assertOwner()
+	rt.enter()
+	defer rt.exit()
+	for {
+		var ctx_ref *C.JSContext
+		status := C.JS_ExecutePendingJob(rt.ref, &ctx_ref)
+		if status == 0 {
+			return nil
+		}
+		if status < 0 {
+			return contextFromRef(ctx_ref).takeException()
+		}
+	}
+}
+
+// run the runtime's event loop until no work remains, returning the first unhandled error
+// (a thrown job, a rejected promise without a handler, or an error returned by a [Task]).
+//
+// the loop also stops when `goctx` is cancelled, in which case its error is returned (and any running javascript code gets interrupted).
+// the loop can be run again after it returns, in order to continue with the remaining work.
+//
+// see the comment at the top of `./loop.go` for the steps of each iteration.
+func (rt *Runtime) RunLoop(goctx context.Context) error {
+	return rt.runLoopUntil(goctx, nil, true)
+}
+
+// run the runtime's event loop until nothing is ready for execution, without ever waiting for pending reservations or for future timers.
+// it returns the first unhandled error, just like [Runtime.RunLoop].
+//
+// this is useful for integrating the runtime into a foreign event loop, and for advancing a [FakeClock] step by step in tests.
+func (rt *Runtime) RunReady() error {
+	return rt.runLoopUntil(context.Background(), nil, false)
+}
+
+// run the event loop until no work remains, or until `stop` returns `true` (it is checked right after the microtasks are drained).
+// when `block` is `false`, the loop returns instead of waiting for work that is not ready yet.
+func (rt *Runtime) runLoopUntil(goctx context.Context, stop func() bool, block bool) error {
+	loop := rt.loop
+	rt.enter()
+	defer rt.exit()
+	// the javascript code executed by the loop gets interrupted once `goctx` is done (see `./interrupt.go`).
+	defer rt.bindContext(goctx)()
+	for {
+		if err := goctx.Err(); err != nil {
+			return err
+		}
+		if err := rt.ExecutePendingJobs(); err != nil {
+			return err
+		}
+		if stop != nil && stop() {
+			return nil
+		}
+		if err := loop.takeUnhandledRejection(); err != nil {
+			return err
+		}
+		if due_timer := loop.timers.popDue(); due_timer != nil {
+			if err := loop.timers.fire(due_timer); err != nil {
+				return err
+			}
+			continue
+		}
+		task, has_work := loop.popTask()
+		if task != nil {
+			if err := task(); err != nil {
+				return err
+			}
+			continue
+		}
+		next_timer := loop.timers.next()
+		if !block || (!has_work && next_timer == nil) {
+			return nil
+		}
+		// a `nil` channel blocks forever, which is what we want when there are no timers.
+		var timer_ch <-chan time.Time
+		if next_timer != nil {
+			timer_ch = loop.timers.clock.After(next_timer.deadline.Sub(loop.timers.clock.Now()))
+		}
+		select {
+		case <-goctx.Done():
+			return goctx.Err()
+		case <-loop.wake:
+		case <-timer_ch:
+		}
+	}
+}
+
+// returned by [Value.Await] when the promise is still pending, but the event loop has run out of work that could ever settle it.
+var ErrAwaitStalled = errors.New("the awaited promise can never settle, since the event loop has no remaining work")
+
+// block until the promise settles, while running the runtime's event loop (see [Runtime.RunLoop]), and then return its fulfillment value.
+// if the value is not a promise, then it is returned as is (duplicated), just like javascript's `await`.
+//
+// if the promise gets rejected, the rejection reason is returned as an [*Error].
+// if `goctx` is done before the promise settles, then its error is returned, and the promise remains pending.
+// any other unhandled error encountered by the event loop is returned as well (see [Runtime.RunLoop]).
+//
+// @should-free
+func (val *Value) Await(goctx context.Context) (*Value, error) {
+	if !val.IsPromise() {
+		return val.Dupe(), nil
+	}
+	rt := val.ctx.rt
+	if err := rt.runLoopUntil(goctx, func() bool { return val.PromiseState() != PromisePending }, true); err != nil {
+		return nil, err
+	}
+	switch val.PromiseState() {
+	case PromiseFulfilled:
+		return val.PromiseResult(), nil
+	case PromiseRejected:
+		// since the rejection is being handled by us, it must not be reported as unhandled by the event loop.
+		rt.loop.forgetRejection(val)
+		reason := val.PromiseResult()
+		defer reason.Free()
+		return nil, reason.toThrownError()
+	}
+	return nil, ErrAwaitStalled
+}
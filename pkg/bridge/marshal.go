@@ -0,0 +1,614 @@
+// this file contains the generic reflection-based conversion (marshaling) between go-values and javascript [Value]s.
+//
+// below is a table of how go kinds are mapped to javascript types (in both directions):
+//
+// | go-kind(s)                              | js-type                                            |
+// |-----------------------------------------|----------------------------------------------------|
+// | `bool`                                  | `boolean`                                          |
+// | `int*`, `uint*`, `float32`, `float64`   | `number`                                           |
+// | `string`                                | `string`                                           |
+// | `*math/big.Int`                         | `bigint`                                           |
+// | `time.Time`                             | `Date`                                             |
+// | `[]byte`                                | `Uint8Array` (any `TypedArray` or `ArrayBuffer` when unmarshaling) |
+// | slices and arrays                       | `Array`                                            |
+// | maps (with string or integer keys)      | `Object`                                           |
+// | structs                                 | `Object` (see [Context.Marshal] for struct tags)   |
+// | pointers                                | the pointed value, or `null` when `nil`            |
+// | `*bridge.Value`                         | _any_ (passed through as is)                       |
+// | `any`                                   | _any_ (see [Value.Unmarshal] for the chosen types) |
+
+package bridge
+
+import (
+	errors "errors"
+	fmt "fmt"
+	math "math"
+	math_big "math/big"
+	reflect "reflect"
+	slices "slices"
+	strconv "strconv"
+	strings "strings"
+	time "time"
+	unsafe "unsafe"
+)
+
+var (
+	reflectBigIntType = reflect.TypeFor[math_big.Int]()
+	reflectTimeType   = reflect.TypeFor[time.Time]()
+)
+
+// represents an error that occurred while marshaling or unmarshaling a nested go-value,
+// with the `Path` pointing to the offending element (for instance: `.items[3].name`).
+type MarshalError struct {
+	Path string // the path to the nested element that failed to convert, or an empty string if it was the root value.
+	Err  error  // the underlying reason behind the failure.
+}
+
+// prints the path-qualified error message (to implement the `error` interface).
+func (err *MarshalError) Error() string {
+	if err.Path == "" {
+		return err.Err.Error()
+	}
+	return err.Path + ": " + err.Err.Error()
+}
+
+// returns the underlying error (for compatibility with [errors.Is] and [errors.As]).
+func (err *MarshalError) Unwrap() error {
+	return err.Err
+}
+
+func newMarshalError(path string, err error) error {
+	return &MarshalError{Path: path, Err: err}
+}
+
+//------       MARSHALING      ------//
+
+// convert a go-value `v` into a new javascript [Value], recursively (see the table at the top of this file for the type mappings).
+//
+// struct fields are converted to object properties following the same rules as [Context.BindStruct]:
+//   - only exported fields are included, and their names are converted to camelCase.
+//   - the `js:"name"` struct tag renames a field, while the `js:"-"` tag excludes it.
+//   - the `js:",omitempty"` (or `js:"name,omitempty"`) tag option skips the field when it holds an empty value
+//     (i.e. `false`, `0`, `""`, a `nil` pointer or interface, or an empty slice or map).
+//
+// if the conversion fails, a [*MarshalError] pointing to the offending nested element is returned.
+// this includes go-values that contain a reference cycle (such as a struct pointer or a map that contains itself).
+//
+// @should-free
+func (ctx *Context) Marshal(v any) (*Value, error) {
+	return ctx.marshalReflect(reflect.ValueOf(v), "")
+}
+
+// the implementation of [Context.Marshal], where `path` is the location of `rv` within the root go-value.
+//
+// @should-free
+func (ctx *Context) marshalReflect(rv reflect.Value, path string) (*Value, error) {
+	m := &marshaler{ctx: ctx}
+	return m.marshal(rv, path)
+}
+
+// the state of a single (recursive) marshaling of a go-value.
+type marshaler struct {
+	ctx *Context
+	// the pointers, maps, and slices that enclose the go-value being marshaled, so that reference cycles are reported
+	// instead of recursing forever (similar to `encoding/json`). it is only allocated once the first one is encountered.
+	visiting map[marshalVisit]struct{}
+}
+
+// identifies a pointer, map, or slice. the type is needed since a struct and its first field share the same address,
+// and the length is needed since the sub-slices of a slice share the same address too.
+type marshalVisit struct {
+	ptr    unsafe.Pointer
+	typ    reflect.Type
+	length int
+}
+
+// mark `rv` (a non-`nil` pointer, map, or slice) as being visited, until the returned `leave` function is called.
+// if `rv` is already being visited further up the path, then a reference cycle has been found, and an error is returned instead.
+func (m *marshaler) enter(rv reflect.Value, path string) (leave func(), err error) {
+	visit := marshalVisit{ptr: rv.UnsafePointer(), typ: rv.Type()}
+	if rv.Kind() == reflect.Slice {
+		visit.length = rv.Len()
+	}
+	if m.visiting == nil {
+		m.visiting = map[marshalVisit]struct{}{}
+	}
+	if _, ok := m.visiting[visit]; ok {
+		return nil, newMarshalError(path, fmt.Errorf("encountered a reference cycle via %s", rv.Type()))
+	}
+	m.visiting[visit] = struct{}{}
+	return func() { delete(m.visiting, visit) }, nil
+}
+
+// the recursive implementation of [Context.Marshal].
+//
+// @should-free
+func (m *marshaler) marshal(rv reflect.Value, path string) (*Value, error) {
+	ctx := m.ctx
+	if !rv.IsValid() {
+		// this happens when the root value is a `nil` interface.
+		return ctx.NewNull(), nil
+	}
+	switch rv.Type() {
+	case reflectValueType:
+		// the returned value will be owned by the caller, thus we must increment its reference count.
+		if rv.IsNil() {
+			return ctx.NewUndefined(), nil
+		}
+		return rv.Interface().(*Value).Dupe(), nil
+	case reflectBigIntType:
+		bigint := rv.Interface().(math_big.Int)
+		return ctx.NewBigInt(&bigint), nil
+	case reflectTimeType:
+		return ctx.NewDate(rv.Interface().(time.Time)), nil
+	}
+	switch rv.Kind() {
+	case reflect.Bool:
+		return ctx.NewBool(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return ctx.NewInt64(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if u := rv.Uint(); u <= math.MaxInt64 {
+			return ctx.NewInt64(int64(u)), nil
+		}
+		return ctx.NewFloat64(float64(rv.Uint())), nil
+	case reflect.Float32, reflect.Float64:
+		return ctx.NewFloat64(rv.Float()), nil
+	case reflect.String:
+		return ctx.NewString(rv.String()), nil
+	case reflect.Interface:
+		if rv.IsNil() {
+			return ctx.NewNull(), nil
+		}
+		return m.marshal(rv.Elem(), path)
+	case reflect.Pointer:
+		if rv.IsNil() {
+			return ctx.NewNull(), nil
+		}
+		leave, err := m.enter(rv, path)
+		if err != nil {
+			return nil, err
+		}
+		defer leave()
+		return m.marshal(rv.Elem(), path)
+	case reflect.Slice:
+		if rv.IsNil() {
+			return ctx.NewNull(), nil
+		}
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return ctx.NewTypedArrayFromBytes(TypedArrayUint8, rv.Bytes()), nil
+		}
+		leave, err := m.enter(rv, path)
+		if err != nil {
+			return nil, err
+		}
+		defer leave()
+		return m.marshalArray(rv, path)
+	case reflect.Array:
+		return m.marshalArray(rv, path)
+	case reflect.Map:
+		if rv.IsNil() {
+			return ctx.NewNull(), nil
+		}
+		leave, err := m.enter(rv, path)
+		if err != nil {
+			return nil, err
+		}
+		defer leave()
+		return m.marshalMap(rv, path)
+	case reflect.Struct:
+		return m.marshalStruct(rv, path)
+	}
+	return nil, newMarshalError(path, fmt.Errorf("unsupported go type: %s", rv.Type()))
+}
+
+// @should-free
+func (m *marshaler) marshalArray(rv reflect.Value, path string) (*Value, error) {
+	ctx := m.ctx
+	js_arr := ctx.NewArray()
+	for i := range rv.Len() {
+		js_item, err := m.marshal(rv.Index(i), path+"["+strconv.Itoa(i)+"]")
+		if err != nil {
+			js_arr.Free()
+			return nil, err
+		}
+		js_arr.SetIdx(int64(i), js_item)
+	}
+	return js_arr, nil
+}
+
+// @should-free
+func (m *marshaler) marshalMap(rv reflect.Value, path string) (*Value, error) {
+	ctx := m.ctx
+	keys := make([]string, 0, rv.Len())
+	values := make(map[string]reflect.Value, rv.Len())
+	for iter := rv.MapRange(); iter.Next(); {
+		key, err := mapKeyToString(iter.Key())
+		if err != nil {
+			return nil, newMarshalError(path, err)
+		}
+		keys = append(keys, key)
+		values[key] = iter.Value()
+	}
+	// go maps are unordered, so we sort the keys to make the order of the javascript object's properties deterministic.
+	slices.Sort(keys)
+	js_obj := ctx.NewObject()
+	for _, key := range keys {
+		js_item, err := m.marshal(values[key], path+"."+key)
+		if err != nil {
+			js_obj.Free()
+			return nil, err
+		}
+		js_obj.Set(key, js_item)
+	}
+	return js_obj, nil
+}
+
+// @should-free
+func (m *marshaler) marshalStruct(rv reflect.Value, path string) (*Value, error) {
+	ctx := m.ctx
+	js_obj := ctx.NewObject()
+	for _, field := range reflect.VisibleFields(rv.Type()) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		js_name, options, hidden := parseStructTag(field)
+		if hidden {
+			continue
+		}
+		field_val, err := rv.FieldByIndexErr(field.Index)
+		if err != nil {
+			// the field was promoted from a `nil` embedded struct pointer, so there's nothing to marshal.
+			continue
+		}
+		if hasTagOption(options, "omitempty") && isEmptyReflectValue(field_val) {
+			continue
+		}
+		js_field, err := m.marshal(field_val, path+"."+js_name)
+		if err != nil {
+			js_obj.Free()
+			return nil, err
+		}
+		js_obj.Set(js_name, js_field)
+	}
+	return js_obj, nil
+}
+
+// stringify a go map's key to be used as a javascript object property name.
+func mapKeyToString(key reflect.Value) (string, error) {
+	switch key.Kind() {
+	case reflect.String:
+		return key.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(key.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(key.Uint(), 10), nil
+	}
+	return "", fmt.Errorf("unsupported go map key type: %s", key.Type())
+}
+
+// check if the comma separated struct tag `options` (see [parseStructTag]) include the option `name` (this follows the same rules as `encoding/json`).
+func hasTagOption(options string, name string) bool {
+	for options != "" {
+		var option string
+		option, options, _ = strings.Cut(options, ",")
+		if option == name {
+			return true
+		}
+	}
+	return false
+}
+
+// dictates whether a struct field with the `omitempty` option should be omitted (this follows the same rules as `encoding/json`).
+func isEmptyReflectValue(rv reflect.Value) bool {
+	switch rv.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return rv.Len() == 0
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Interface, reflect.Pointer:
+		return rv.IsZero()
+	}
+	return false
+}
+
+//------      UNMARSHALING     ------//
+
+// convert the javascript value into the go-value pointed to by `dst`, recursively
+// (see the table at the top of this file for the type mappings, and [Context.Marshal] for the struct tag rules).
+//
+// the conversion is strict, meaning that no javascript type coercion takes place (i.e. a `string` won't be converted to a `number`).
+// moreover:
+//   - object properties that are missing (i.e. `undefined`) leave their corresponding struct fields untouched.
+//   - `null` and `undefined` set pointers, interfaces, slices, and maps to `nil`.
+//   - when unmarshaling into an empty interface (`any`), the following go-types are chosen:
+//     `nil`, `bool`, `float64`, `string`, `*big.Int`, `time.Time`, `[]byte` (for `Uint8Array`s and `ArrayBuffer`s),
+//     `[]any` (for `Array`s), and `map[string]any` (for all other objects).
+//   - a [*Value] destination receives a duplicate of the javascript value, which you must free yourself.
+//
+// if the conversion fails, a [*MarshalError] pointing to the offending nested element is returned.
+func (val *Value) Unmarshal(dst any) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("[Value.Unmarshal]: expected a non-nil pointer destination, got: %T", dst)
+	}
+	return val.unmarshalReflect(rv.Elem(), "")
+}
+
+// the recursive implementation of [Value.Unmarshal], where `rv` must be settable,
+// and `path` is the location of `val` within the root javascript value.
+func (val *Value) unmarshalReflect(rv reflect.Value, path string) error {
+	rv_type := rv.Type()
+	switch rv_type {
+	case reflectValueType:
+		rv.Set(reflect.ValueOf(val.Dupe()))
+		return nil
+	case reflectBigIntType:
+		if !val.IsBigInt() {
+			return newMarshalError(path, val.typeMismatch("bigint"))
+		}
+		rv.Set(reflect.ValueOf(*val.ToBigInt()))
+		return nil
+	case reflectTimeType:
+		if !val.IsDate() {
+			return newMarshalError(path, val.typeMismatch("Date"))
+		}
+		rv.Set(reflect.ValueOf(val.ToTime()))
+		return nil
+	}
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map:
+		if val.IsNull() || val.IsUndefined() {
+			rv.SetZero()
+			return nil
+		}
+	}
+	switch rv.Kind() {
+	case reflect.Bool:
+		if !val.IsBool() {
+			return newMarshalError(path, val.typeMismatch("boolean"))
+		}
+		rv.SetBool(val.ToBool())
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if !val.IsNumber() {
+			return newMarshalError(path, val.typeMismatch("number"))
+		}
+		f := val.ToFloat64()
+		if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 || rv.OverflowInt(int64(f)) {
+			return newMarshalError(path, fmt.Errorf("the number %v does not fit in %s", f, rv_type))
+		}
+		rv.SetInt(int64(f))
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if !val.IsNumber() {
+			return newMarshalError(path, val.typeMismatch("number"))
+		}
+		f := val.ToFloat64()
+		if f != math.Trunc(f) || f < 0 || f >= math.MaxUint64 || rv.OverflowUint(uint64(f)) {
+			return newMarshalError(path, fmt.Errorf("the number %v does not fit in %s", f, rv_type))
+		}
+		rv.SetUint(uint64(f))
+		return nil
+	case reflect.Float32, reflect.Float64:
+		if !val.IsNumber() {
+			return newMarshalError(path, val.typeMismatch("number"))
+		}
+		rv.SetFloat(val.ToFloat64())
+		return nil
+	case reflect.String:
+		if !val.IsString() {
+			return newMarshalError(path, val.typeMismatch("string"))
+		}
+		rv.SetString(val.ToString())
+		return nil
+	case reflect.Pointer:
+		elem := rv
+		if rv.IsNil() {
+			elem = reflect.New(rv_type.Elem())
+		}
+		if err := val.unmarshalReflect(elem.Elem(), path); err != nil {
+			return err
+		}
+		rv.Set(elem)
+		return nil
+	case reflect.Interface:
+		if rv.NumMethod() != 0 {
+			break
+		}
+		go_val, err := val.unmarshalAny(path)
+		if err != nil {
+			return err
+		}
+		rv.Set(reflect.ValueOf(&go_val).Elem())
+		return nil
+	case reflect.Slice:
+		if rv_type.Elem().Kind() == reflect.Uint8 && (val.IsArrayBuffer() || val.IsTypedArray(TypedArrayAny)) {
+			rv.SetBytes(val.ToByteArray())
+			return nil
+		}
+		if !val.IsArray() {
+			return newMarshalError(path, val.typeMismatch("Array"))
+		}
+		length := int(val.Len())
+		rv.Set(reflect.MakeSlice(rv_type, length, length))
+		return val.unmarshalArray(rv, length, path)
+	case reflect.Array:
+		if !val.IsArray() {
+			return newMarshalError(path, val.typeMismatch("Array"))
+		}
+		// just like `encoding/json`, excess elements are dropped, while missing elements are zeroed.
+		rv.SetZero()
+		return val.unmarshalArray(rv, min(int(val.Len()), rv.Len()), path)
+	case reflect.Map:
+		if !val.IsObject() || val.IsArray() {
+			return newMarshalError(path, val.typeMismatch("object"))
+		}
+		return val.unmarshalMap(rv, path)
+	case reflect.Struct:
+		if !val.IsObject() || val.IsArray() {
+			return newMarshalError(path, val.typeMismatch("object"))
+		}
+		return val.unmarshalStruct(rv, path)
+	}
+	return newMarshalError(path, fmt.Errorf("unsupported go type: %s", rv_type))
+}
+
+func (val *Value) unmarshalArray(rv reflect.Value, length int, path string) error {
+	for i := range length {
+		js_item := val.GetIdx(int64(i))
+		err := js_item.unmarshalReflect(rv.Index(i), path+"["+strconv.Itoa(i)+"]")
+		js_item.Free()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (val *Value) unmarshalMap(rv reflect.Value, path string) error {
+	rv_type := rv.Type()
+	if rv.IsNil() {
+		rv.Set(reflect.MakeMap(rv_type))
+	}
+	entries := val.GetEntries()
+	defer func() {
+		// the entries that were not consumed (due to an early error) must still be freed.
+		for _, entry := range entries {
+			entry.Value.Free()
+		}
+	}()
+	for len(entries) > 0 {
+		entry := entries[0]
+		entries = entries[1:]
+		key := reflect.New(rv_type.Key()).Elem()
+		err := stringToMapKey(entry.Key, key)
+		if err == nil {
+			item := reflect.New(rv_type.Elem()).Elem()
+			if err = entry.Value.unmarshalReflect(item, path+"."+entry.Key); err == nil {
+				rv.SetMapIndex(key, item)
+			}
+		} else {
+			err = newMarshalError(path+"."+entry.Key, err)
+		}
+		entry.Value.Free()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (val *Value) unmarshalStruct(rv reflect.Value, path string) error {
+	for _, field := range reflect.VisibleFields(rv.Type()) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		js_name, _, hidden := parseStructTag(field)
+		if hidden {
+			continue
+		}
+		js_field := val.Get(js_name)
+		if js_field.IsUndefined() {
+			continue
+		}
+		field_val, err := rv.FieldByIndexErr(field.Index)
+		if err == nil {
+			err = js_field.unmarshalReflect(field_val, path+"."+js_name)
+		} else {
+			err = newMarshalError(path+"."+js_name, err)
+		}
+		js_field.Free()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// convert a javascript value to the natural go-type that an empty interface (`any`) would hold (see [Value.Unmarshal]).
+func (val *Value) unmarshalAny(path string) (any, error) {
+	switch {
+	case val.IsNull(), val.IsUndefined():
+		return nil, nil
+	case val.IsBool():
+		return val.ToBool(), nil
+	case val.IsNumber():
+		return val.ToFloat64(), nil
+	case val.IsString():
+		return val.ToString(), nil
+	case val.IsBigInt():
+		return val.ToBigInt(), nil
+	case val.IsFunction(), val.IsSymbol():
+		return nil, newMarshalError(path, fmt.Errorf(`unsupported javascript type: "%s"`, val.typeOf()))
+	case val.IsDate():
+		return val.ToTime(), nil
+	case val.IsArrayBuffer(), val.IsTypedArray(TypedArrayUint8):
+		return val.ToByteArray(), nil
+	case val.IsArray():
+		var items []any
+		err := val.unmarshalReflect(reflect.ValueOf(&items).Elem(), path)
+		return items, err
+	}
+	var items map[string]any
+	err := val.unmarshalReflect(reflect.ValueOf(&items).Elem(), path)
+	return items, err
+}
+
+// parse a javascript object's property name into a go map's key.
+func stringToMapKey(key string, rv reflect.Value) error {
+	switch rv.Kind() {
+	case reflect.String:
+		rv.SetString(key)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(key, 10, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetInt(n)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(key, 10, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetUint(n)
+		return nil
+	}
+	return errors.New("unsupported go map key type: " + rv.Type().String())
+}
+
+// create an error describing a mismatch between the `expected` javascript type and the actual type of the value.
+func (val *Value) typeMismatch(expected string) error {
+	actual := val.typeOf()
+	if val.IsNull() {
+		actual = "null"
+	}
+	return fmt.Errorf("expected %s, got %s", expected, actual)
+}
+
+// returns the name of the value's javascript type, identical to what the `typeof val` javascript operator would return.
+// the possible results are: `"undefined"`, `"object"`, `"boolean"`, `"number"`, `"bigint"`, `"string"`, `"symbol"`, and `"function"`.
+//
+// just like in javascript, the type of `null` is reported as `"object"`.
+func (val *Value) typeOf() string {
+	switch {
+	case val.IsUndefined(), val.IsUninitialized():
+		return "undefined"
+	case val.IsBool():
+		return "boolean"
+	case val.IsNumber():
+		return "number"
+	case val.IsBigInt():
+		return "bigint"
+	case val.IsString():
+		return "string"
+	case val.IsSymbol():
+		return "symbol"
+	case val.IsFunction():
+		return "function"
+	default:
+		return "object"
+	}
+}
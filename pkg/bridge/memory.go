@@ -0,0 +1,141 @@
+// this file contains the memory usage statistics of a [Runtime], based on quickjs's `JS_ComputeMemoryUsage`.
+
+package bridge
+
+/*
+#include "./include0_quickjs.h"
+*/
+import "C"
+import (
+	fmt "fmt"
+	io "io"
+	strconv "strconv"
+)
+
+// a snapshot of the memory usage of a [Runtime] (see [Runtime.MemoryUsage]), where all sizes are in bytes.
+//
+// since the struct is made of plain numbers, two snapshots can be compared directly (for instance, before and after running some code in a leak test).
+type MemoryUsage struct {
+	MallocSize      int64 // the number of bytes allocated by the runtime's allocator.
+	MallocLimit     int64 // the memory limit of the runtime (see [Runtime.SetMemoryLimit]).
+	MemoryUsedSize  int64 // the number of bytes in use by the runtime, excluding the allocator's overhead.
+	MallocCount     int64 // the number of allocated memory blocks.
+	MemoryUsedCount int64 // the number of memory blocks in use.
+
+	AtomCount   int64
+	AtomSize    int64
+	StringCount int64
+	StringSize  int64
+	ObjectCount int64
+	ObjectSize  int64
+	// the properties and shapes of all objects.
+	PropertyCount int64
+	PropertySize  int64
+	ShapeCount    int64
+	ShapeSize     int64
+
+	// the bytecode functions, where `FunctionSize` includes their bytecode (`FunctionCodeSize`) and debug information (`FunctionPc2LineSize`).
+	FunctionCount        int64
+	FunctionSize         int64
+	FunctionCodeSize     int64
+	FunctionPc2LineCount int64
+	FunctionPc2LineSize  int64
+	CFunctionCount       int64 // the number of c-functions (which includes the go-functions created via [Context.NewFunction]).
+
+	ArrayCount        int64
+	FastArrayCount    int64 // the number of arrays whose elements are stored contiguously.
+	FastArrayElements int64 // the total number of elements inside of the fast arrays.
+	// the binary objects (the backing stores of `ArrayBuffer`s and typed arrays).
+	BinaryObjectCount int64
+	BinaryObjectSize  int64
+}
+
+// compute a snapshot of the runtime's memory usage.
+//
+// the computation walks over every object of the runtime, so avoid calling it in a hot path.
+func (rt *Runtime) MemoryUsage() MemoryUsage {
+	var s C.JSMemoryUsage
+	C.JS_ComputeMemoryUsage(rt.ref, &s)
+	return MemoryUsage{
+		MallocSize:           int64(s.malloc_size),
+		MallocLimit:          int64(s.malloc_limit),
+		MemoryUsedSize:       int64(s.memory_used_size),
+		MallocCount:          int64(s.malloc_count),
+		MemoryUsedCount:      int64(s.memory_used_count),
+		AtomCount:            int64(s.atom_count),
+		AtomSize:             int64(s.atom_size),
+		StringCount:          int64(s.str_count),
+		StringSize:           int64(s.str_size),
+		ObjectCount:          int64(s.obj_count),
+		ObjectSize:           int64(s.obj_size),
+		PropertyCount:        int64(s.prop_count),
+		PropertySize:         int64(s.prop_size),
+		ShapeCount:           int64(s.shape_count),
+		ShapeSize:            int64(s.shape_size),
+		FunctionCount:        int64(s.js_func_count),
+		FunctionSize:         int64(s.js_func_size),
+		FunctionCodeSize:     int64(s.js_func_code_size),
+		FunctionPc2LineCount: int64(s.js_func_pc2line_count),
+		FunctionPc2LineSize:  int64(s.js_func_pc2line_size),
+		CFunctionCount:       int64(s.c_func_count),
+		ArrayCount:           int64(s.array_count),
+		FastArrayCount:       int64(s.fast_array_count),
+		FastArrayElements:    int64(s.fast_array_elements),
+		BinaryObjectCount:    int64(s.binary_object_count),
+		BinaryObjectSize:     int64(s.binary_object_size),
+	}
+}
+
+// the size of a `JSValue` in bytes, which is needed for computing the size of the elements of fast arrays.
+const jsValueSize = int64(C.sizeof_JSValue)
+
+// write a human-readable table of the runtime's memory usage to `w`, mirroring the output of quickjs's `JS_DumpMemoryUsage`
+// (except for the per-class object counts, which quickjs only collects in its debug builds).
+func (rt *Runtime) DumpMemoryUsage(w io.Writer) error {
+	s := rt.MemoryUsage()
+	var err error
+	printf := func(format string, args ...any) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, format, args...)
+		}
+	}
+	per := func(size int64, count int64) float64 { return float64(size) / float64(count) }
+	printf("QuickJS memory usage -- %s version, %d-bit, malloc limit: %d\n\n", QuickjsVersion, strconv.IntSize, s.MallocLimit)
+	printf("%-20s %8s %8s\n", "NAME", "COUNT", "SIZE")
+	if s.MallocCount != 0 {
+		printf("%-20s %8d %8d  (%0.1f per block)\n", "memory allocated", s.MallocCount, s.MallocSize, per(s.MallocSize, s.MallocCount))
+		printf("%-20s %8d %8d  (%0.1f average slack)\n", "memory used", s.MemoryUsedCount, s.MemoryUsedSize, per(s.MallocSize-s.MemoryUsedSize, s.MemoryUsedCount))
+	}
+	if s.AtomCount != 0 {
+		printf("%-20s %8d %8d  (%0.1f per atom)\n", "atoms", s.AtomCount, s.AtomSize, per(s.AtomSize, s.AtomCount))
+	}
+	if s.StringCount != 0 {
+		printf("%-20s %8d %8d  (%0.1f per string)\n", "strings", s.StringCount, s.StringSize, per(s.StringSize, s.StringCount))
+	}
+	if s.ObjectCount != 0 {
+		printf("%-20s %8d %8d  (%0.1f per object)\n", "objects", s.ObjectCount, s.ObjectSize, per(s.ObjectSize, s.ObjectCount))
+		printf("%-20s %8d %8d  (%0.1f per object)\n", "  properties", s.PropertyCount, s.PropertySize, per(s.PropertyCount, s.ObjectCount))
+		printf("%-20s %8d %8d  (%0.1f per shape)\n", "  shapes", s.ShapeCount, s.ShapeSize, per(s.ShapeSize, s.ShapeCount))
+	}
+	if s.FunctionCount != 0 {
+		printf("%-20s %8d %8d\n", "bytecode functions", s.FunctionCount, s.FunctionSize)
+		printf("%-20s %8d %8d  (%0.1f per function)\n", "  bytecode", s.FunctionCount, s.FunctionCodeSize, per(s.FunctionCodeSize, s.FunctionCount))
+		if s.FunctionPc2LineCount != 0 {
+			printf("%-20s %8d %8d  (%0.1f per function)\n", "  pc2line", s.FunctionPc2LineCount, s.FunctionPc2LineSize, per(s.FunctionPc2LineSize, s.FunctionPc2LineCount))
+		}
+	}
+	if s.CFunctionCount != 0 {
+		printf("%-20s %8d\n", "C functions", s.CFunctionCount)
+	}
+	if s.ArrayCount != 0 {
+		printf("%-20s %8d\n", "arrays", s.ArrayCount)
+	}
+	if s.FastArrayCount != 0 {
+		printf("%-20s %8d\n", "fast arrays", s.FastArrayCount)
+		printf("%-20s %8d %8d  (%0.1f per fast array)\n", "  elements", s.FastArrayElements, s.FastArrayElements*jsValueSize, per(s.FastArrayElements, s.FastArrayCount))
+	}
+	if s.BinaryObjectCount != 0 {
+		printf("%-20s %8d %8d\n", "binary objects", s.BinaryObjectCount, s.BinaryObjectSize)
+	}
+	return err
+}
@@ -0,0 +1,162 @@
+// this file contains the evaluation of es-modules, along with the pluggable [ModuleLoader] interface,
+// which quickjs consults whenever an `import` statement (or a dynamic `import()`) needs to be resolved.
+
+package bridge
+
+/*
+#include "./include1_helpers.h"
+
+// forward declarations of the module resolution callback functions, otherwise the compiler won't discover them.
+JSModuleNormalizeFunc goModuleNormalize;
+JSModuleLoaderFunc goModuleLoader;
+*/
+import "C"
+import (
+	errors "errors"
+	fmt "fmt"
+	unsafe "unsafe"
+)
+
+// a ModuleLoader resolves and loads the source code of es-modules imported by javascript.
+//
+// set it on a runtime via [Runtime.SetModuleLoader].
+type ModuleLoader interface {
+	// resolve an import `specifier` (such as `"./utils.js"`), found inside of the module named `base`,
+	// to the absolute name of the module that should be loaded.
+	// the returned name is what quickjs uses to cache the module, and it is then passed to [ModuleLoader.Load].
+	Normalize(base, specifier string) (string, error)
+	// load the javascript source code of the module with the given normalized `name`.
+	Load(name string) (source string, err error)
+}
+
+// set the module loader that resolves the `import` statements of all contexts of this runtime.
+//
+// passing a `nil` loader removes the current loader, after which any `import` will fail.
+func (rt *Runtime) SetModuleLoader(loader ModuleLoader) {
+	rt.moduleLoader = loader
+	if loader == nil {
+		C.JS_SetModuleLoaderFunc(rt.ref, nil, nil, nil)
+		return
+	}
+	C.JS_SetModuleLoaderFunc(rt.ref, &C.goModuleNormalize, &C.goModuleLoader, nil)
+}
+
+//export goModuleNormalize
+func goModuleNormalize(ctx_ref *C.JSContext, c_base *C.const_char_t, c_specifier *C.const_char_t, opaque unsafe.Pointer) (c_name *C.char) {
+	ctx := contextFromRef(ctx_ref)
+	defer ctx.recoverModuleLoader(func() { c_name = nil })
+	base := C.GoString((*C.char)(unsafe.Pointer(c_base)))
+	specifier := C.GoString((*C.char)(unsafe.Pointer(c_specifier)))
+	// native modules take precedence over the loader, and since they're already registered, quickjs will find them without loading.
+	if _, ok := ctx.nativeModules[specifier]; ok {
+		return ctx.newMallocString(specifier)
+	}
+	name, err := ctx.rt.moduleLoader.Normalize(base, specifier)
+	if err != nil {
+		C.JS_Throw(ctx.ref, ctx.NewError(fmt.Errorf(`could not resolve the module "%s" imported by "%s": %w`, specifier, base, err)).transfer())
+		return nil
+	}
+	return ctx.newMallocString(name)
+}
+
+// a panic inside of a [ModuleLoader] must never unwind through quickjs's c-frames, so just like [GoFunction]s,
+// it is converted into a javascript exception carrying a [*PanicError], after which `fail` sets the callback's failure result.
+// this must be deferred directly by the callback, since `recover` only works there.
+func (ctx *Context) recoverModuleLoader(fail func()) {
+	if recovered := recover(); recovered != nil {
+		C.JS_Throw(ctx.ref, ctx.NewError(newPanicError(recovered)).transfer())
+		fail()
+	}
+}
+
+// copy a go-string to a null-terminated c-string that is allocated via `js_malloc`.
+// this is needed when quickjs takes ownership of a returned string, as it will release it via `js_free`.
+func (ctx *Context) newMallocString(str string) *C.char {
+	c_str := (*C.char)(C.js_malloc(ctx.ref, C.size_t(len(str)+1)))
+	if c_str == nil {
+		return nil
+	}
+	c_str_bytes := unsafe.Slice((*byte)(unsafe.Pointer(c_str)), len(str)+1)
+	copy(c_str_bytes, str)
+	c_str_bytes[len(str)] = 0
+	return c_str
+}
+
+//export goModuleLoader
+func goModuleLoader(ctx_ref *C.JSContext, c_name *C.const_char_t, opaque unsafe.Pointer) (module_def *C.JSModuleDef) {
+	ctx := contextFromRef(ctx_ref)
+	defer ctx.recoverModuleLoader(func() { module_def = nil })
+	name := C.GoString((*C.char)(unsafe.Pointer(c_name)))
+	source, err := ctx.rt.moduleLoader.Load(name)
+	if err != nil {
+		C.JS_Throw(ctx.ref, ctx.NewError(fmt.Errorf(`could not load the module "%s": %w`, name, err)).transfer())
+		return nil
+	}
+	func_val := ctx.compileOnly(name, source, js_EVAL_TYPE_MODULE_COMPILE)
+	if C.JS_IsException(func_val) != 0 {
+		// the syntax error remains pending in the context, so that quickjs can forward it to the importer.
+		return nil
+	}
+	module_def = C.valueToModuleDef(func_val)
+	// the compiled module is kept alive by the context's module list, so our reference to it is no longer needed.
+	C.JS_FreeValue(ctx.ref, func_val)
+	return module_def
+}
+
+// compile (but don't evaluate) the source code of a script or an es-module, depending on the compile-only `eval_type`.
+// note that compiling an es-module also resolves (and loads) its imports.
+// on failure, the exception value is returned, and the error is left pending in the context.
+//
+// @should-free
+func (ctx *Context) compileOnly(name, code string, eval_type js_EVAL_TYPE) C.JSValue {
+	c_code := C.CString(code)
+	c_name := C.CString(name)
+	defer C.free(unsafe.Pointer(c_code))
+	defer C.free(unsafe.Pointer(c_name))
+	return C.JS_Eval(ctx.ref, c_code, C.size_t(len(code)), c_name, C.int(eval_type))
+}
+
+// evaluate the source `code` of an es-module, and return its namespace object (i.e. the object holding all of its exports).
+//
+// the `name` is the module's own name, which serves as the `base` when resolving its relative imports (see [ModuleLoader]).
+// any imports are resolved through the runtime's [ModuleLoader] (see [Runtime.SetModuleLoader]).
+//
+// if the module (or one of its dependencies) uses top-level `await`, then its evaluation may not have finished when this method returns,
+// in which case the exports that are assigned after the `await` remain uninitialized until the context's pending jobs are executed.
+//
+// @should-free
+func (ctx *Context) EvalModule(name, code string) (*Value, error) {
+	if ctx.ref == nil {
+		return nil, errors.New("context is nil")
+	}
+	func_val := ctx.compileOnly(name, code, js_EVAL_TYPE_MODULE_COMPILE)
+	if C.JS_IsException(func_val) != 0 {
+		return nil, ctx.takeException()
+	}
+	return ctx.evalModuleFunction(func_val)
+}
+
+// evaluate a compiled (and resolved) es-module, consuming `func_val`, and return its namespace object.
+//
+// @should-free
+func (ctx *Context) evalModuleFunction(func_val C.JSValue) (*Value, error) {
+	ctx.rt.enter()
+	defer ctx.rt.exit()
+	module_def := C.valueToModuleDef(func_val)
+	// `JS_EvalFunction` consumes the compiled module, and returns a promise of its evaluation.
+	result := ctx.newValue(C.JS_EvalFunction(ctx.ref, func_val))
+	if result.IsException() {
+		return nil, ctx.takeException()
+	}
+	defer result.Free()
+	if result.PromiseState() == PromiseRejected {
+		reason := result.PromiseResult()
+		defer reason.Free()
+		return nil, reason.toThrownError()
+	}
+	namespace := ctx.newValue(C.JS_GetModuleNamespace(ctx.ref, module_def))
+	if namespace.IsException() {
+		return nil, ctx.takeException()
+	}
+	return namespace, nil
+}
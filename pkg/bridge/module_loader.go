@@ -0,0 +1,152 @@
+// this file contains the ready-made [ModuleLoader]s, which load es-modules from a directory on disk, or from an [fs.FS] (such as an [embed.FS]).
+//
+// module names are normalized to absolute slash-separated paths relative to the loader's root (for example: `"/lib/utils.js"`),
+// and the following kinds of import specifiers are supported:
+//   - relative specifiers (`"./utils.js"` and `"../lib/utils.js"`), which get resolved against the importing module's directory.
+//   - absolute specifiers (`"/lib/utils.js"`), which get resolved against the loader's root.
+//   - bare specifiers (`"lodash"` or `"lodash/fp.js"`), which must be mapped to a path via the loader's [FSModuleLoader.ImportMap].
+//
+// when a specifier does not name an existing file, the extensions `.js` and `.mjs`, and the `/index.js` suffix, are probed (in that order).
+// any specifier that would escape the loader's root directory is rejected.
+
+package bridge
+
+import (
+	fmt "fmt"
+	fs "io/fs"
+	os "os"
+	path "path"
+	strings "strings"
+)
+
+// the suffixes appended to a module path that does not exist as is, in the order that they are probed.
+var moduleProbeSuffixes = []string{".js", ".mjs", "/index.js"}
+
+// a [ModuleLoader] that reads es-modules from an [fs.FS].
+//
+// since [embed.FS] implements [fs.FS], you can use this loader to ship your scripts inside of your native binary:
+//
+//	//go:embed scripts/*
+//	var scripts embed.FS
+//
+//	scripts_root, _ := fs.Sub(scripts, "scripts")
+//	rt.SetModuleLoader(bridge.NewFSModuleLoader(scripts_root))
+//	ctx.EvalModule("/main.js", `import { run } from "./app.js"; run();`)
+type FSModuleLoader struct {
+	fsys fs.FS
+	// the directory opened by [NewDirModuleLoader], which is closed by [FSModuleLoader.Close], or `nil` for loaders over an [fs.FS].
+	root *os.Root
+	// maps bare specifiers to module paths (relative to the loader's root), similar to the `"imports"` field of html import maps.
+	// a key ending with a slash (such as `"lodash/"`) maps all specifiers that begin with it, by substituting the prefix.
+	// when multiple prefix keys match, the longest one wins. targets are always relative to the root, with or without a leading slash.
+	//
+	// example: `{"lodash": "/vendor/lodash/index.js", "lodash/": "vendor/lodash/"}`
+	ImportMap map[string]string
+}
+
+// create a module loader that reads es-modules from the given file system.
+func NewFSModuleLoader(fsys fs.FS) *FSModuleLoader {
+	return &FSModuleLoader{fsys: fsys, ImportMap: map[string]string{}}
+}
+
+// create a module loader that reads es-modules from the directory `dir` on disk.
+//
+// the directory is opened via [os.OpenRoot], so not even symbolic links can be used for escaping it.
+// the loader keeps the directory open until [FSModuleLoader.Close] is called, which you should do once no more modules will be loaded.
+func NewDirModuleLoader(dir string) (*FSModuleLoader, error) {
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+	loader := NewFSModuleLoader(root.FS())
+	loader.root = root
+	return loader, nil
+}
+
+// close the directory opened by [NewDirModuleLoader], after which the loader can no longer load any modules.
+// for loaders created via [NewFSModuleLoader], this does nothing, since the [fs.FS] belongs to you.
+func (loader *FSModuleLoader) Close() error {
+	if loader.root == nil {
+		return nil
+	}
+	return loader.root.Close()
+}
+
+func (loader *FSModuleLoader) Normalize(base, specifier string) (string, error) {
+	if mapped, ok := loader.mapSpecifier(specifier); ok {
+		// mapped specifiers are always resolved relative to the root, regardless of which module imported them.
+		// this includes targets without a leading slash or dot (such as `"vendor/lib.js"`), which would otherwise be taken for bare specifiers.
+		if !strings.HasPrefix(mapped, "/") && !strings.HasPrefix(mapped, "./") && !strings.HasPrefix(mapped, "../") {
+			mapped = "/" + mapped
+		}
+		base, specifier = "/", mapped
+	}
+	var root_relative string
+	switch {
+	case strings.HasPrefix(specifier, "/"):
+		root_relative = path.Clean(specifier[1:])
+	case strings.HasPrefix(specifier, "./") || strings.HasPrefix(specifier, "../"):
+		// modules evaluated with a non-absolute name (such as `"<eval>"`) get resolved relative to the root.
+		base_dir := "."
+		if strings.HasPrefix(base, "/") {
+			base_dir = path.Dir(base[1:])
+		}
+		root_relative = path.Join(base_dir, specifier)
+	default:
+		return "", fmt.Errorf(`the bare specifier "%s" is not present in the import map`, specifier)
+	}
+	if root_relative == ".." || strings.HasPrefix(root_relative, "../") {
+		return "", fmt.Errorf(`the specifier "%s" escapes the root directory`, specifier)
+	}
+	return loader.probe(root_relative)
+}
+
+func (loader *FSModuleLoader) Load(name string) (string, error) {
+	source, err := fs.ReadFile(loader.fsys, strings.TrimPrefix(name, "/"))
+	if err != nil {
+		return "", err
+	}
+	return string(source), nil
+}
+
+// resolve a bare specifier through the import map, returning `false` if it isn't mapped.
+func (loader *FSModuleLoader) mapSpecifier(specifier string) (string, bool) {
+	if mapped, ok := loader.ImportMap[specifier]; ok {
+		return mapped, true
+	}
+	best_prefix := ""
+	for prefix := range loader.ImportMap {
+		if strings.HasSuffix(prefix, "/") && strings.HasPrefix(specifier, prefix) && len(prefix) > len(best_prefix) {
+			best_prefix = prefix
+		}
+	}
+	if best_prefix == "" {
+		return "", false
+	}
+	return loader.ImportMap[best_prefix] + specifier[len(best_prefix):], true
+}
+
+// find the first existing file among the `root_relative` path and its probed suffixes, and return its absolute module name.
+func (loader *FSModuleLoader) probe(root_relative string) (string, error) {
+	candidates := []string{"index.js"}
+	if root_relative != "." {
+		candidates = []string{root_relative}
+		for _, suffix := range moduleProbeSuffixes {
+			candidates = append(candidates, root_relative+suffix)
+		}
+	}
+	for _, candidate := range candidates {
+		if loader.isFile(candidate) {
+			return "/" + candidate, nil
+		}
+	}
+	return "", fmt.Errorf(`no module found at "/%s": %w`, root_relative, fs.ErrNotExist)
+}
+
+func (loader *FSModuleLoader) isFile(name string) bool {
+	info, err := fs.Stat(loader.fsys, name)
+	if err != nil {
+		return false
+	}
+	return info.Mode().IsRegular()
+}
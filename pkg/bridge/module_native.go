@@ -0,0 +1,108 @@
+// this file contains the registration of go-implemented es-modules (native modules),
+// which javascript can import by name (for instance: `import { readFile } from "go:fs"`), without the need for any global objects.
+
+package bridge
+
+/*
+#include "./include1_helpers.h"
+
+// forward declaration of the native module initialization callback function, otherwise the compiler won't discover it.
+JSModuleInitFunc goNativeModuleInit;
+*/
+import "C"
+import (
+	fmt "fmt"
+	reflect "reflect"
+	slices "slices"
+	unsafe "unsafe"
+)
+
+// a go-implemented es-module, registered via [Context.RegisterNativeModule].
+type nativeModule struct {
+	def *C.JSModuleDef
+	// the javascript values of the module's exports, which get freed when the context exits.
+	exports map[string]*Value
+}
+
+// the initialization only hands our exports over to quickjs, but a panic in the process (such as a failed debug assertion)
+// must still not unwind through quickjs's c-frames (see [Context.recoverModuleLoader]).
+//
+//export goNativeModuleInit
+func goNativeModuleInit(ctx_ref *C.JSContext, module_def *C.JSModuleDef) (status C.int) {
+	ctx := contextFromRef(ctx_ref)
+	defer ctx.recoverModuleLoader(func() { status = -1 })
+	for _, module := range ctx.nativeModules {
+		if module.def != module_def {
+			continue
+		}
+		for export_name, export_val := range module.exports {
+			c_export_name := C.CString(export_name)
+			// `JS_SetModuleExport` takes ownership of the value, while our copy must survive until the context exits.
+			export_status := C.JS_SetModuleExport(ctx.ref, module_def, c_export_name, export_val.Dupe().transfer())
+			C.free(unsafe.Pointer(c_export_name))
+			if export_status != 0 {
+				return -1
+			}
+		}
+		return 0
+	}
+	return -1
+}
+
+// register a go-implemented es-module under the given `name`, so that javascript modules can import its `exports` by name.
+// for instance, after registering a `"go:fs"` module with a `"readFile"` export, a module may `import { readFile } from "go:fs"`.
+//
+// native module names are resolved before the runtime's [ModuleLoader] is consulted, so they can't be shadowed by files.
+// the exported go-values are converted to javascript as follows:
+//   - a [GoFunction] becomes a javascript function (see [Context.NewFunction]).
+//   - any other go-function becomes a javascript function whose arguments and results are converted via reflection (see [Context.BindStruct]).
+//   - every other value is converted via [Context.Marshal].
+func (ctx *Context) RegisterNativeModule(name string, exports map[string]any) error {
+	if _, ok := ctx.nativeModules[name]; ok {
+		return fmt.Errorf(`[Context.RegisterNativeModule]: a native module named "%s" is already registered.`, name)
+	}
+	// we sort the export names so that the order of the module namespace's properties remains deterministic.
+	export_names := make([]string, 0, len(exports))
+	for export_name := range exports {
+		export_names = append(export_names, export_name)
+	}
+	slices.Sort(export_names)
+	module := &nativeModule{exports: map[string]*Value{}}
+	for _, export_name := range export_names {
+		export_val, err := ctx.newExportValue(export_name, exports[export_name])
+		if err != nil {
+			return fmt.Errorf(`[Context.RegisterNativeModule]: cannot convert the export "%s" of module "%s": %w`, export_name, name, err)
+		}
+		export_val.FreeOnExit()
+		module.exports[export_name] = export_val
+	}
+	c_name := C.CString(name)
+	defer C.free(unsafe.Pointer(c_name))
+	module.def = C.JS_NewCModule(ctx.ref, c_name, &C.goNativeModuleInit)
+	if module.def == nil {
+		return ctx.takeException()
+	}
+	for _, export_name := range export_names {
+		c_export_name := C.CString(export_name)
+		status := C.JS_AddModuleExport(ctx.ref, module.def, c_export_name)
+		C.free(unsafe.Pointer(c_export_name))
+		if status != 0 {
+			return ctx.takeException()
+		}
+	}
+	ctx.nativeModules[name] = module
+	return nil
+}
+
+// convert a go-value to the javascript value of a native module's export (see [Context.RegisterNativeModule]).
+//
+// @should-free
+func (ctx *Context) newExportValue(name string, v any) (*Value, error) {
+	if fn, ok := v.(GoFunction); ok {
+		return ctx.NewFunction(name, 0, fn), nil
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Func && !rv.IsNil() {
+		return ctx.newReflectFunction(name, rv), nil
+	}
+	return ctx.Marshal(v)
+}
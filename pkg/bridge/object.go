@@ -29,7 +29,7 @@ import (
 //
 // @should-free
 func (ctx *Context) NewObject() *Value {
-	return &Value{ctx: ctx, ref: C.JS_NewObject(ctx.ref)}
+	return ctx.newValue(C.JS_NewObject(ctx.ref))
 }
 
 // set a javascript `Object`'s property `prop` to a certain value `val`.
@@ -45,13 +45,50 @@ func (ctx *Context) NewObject() *Value {
 func (obj *Value) Set(prop string, val *Value) {
 	cstr_ptr := C.CString(prop)
 	defer C.free(unsafe.Pointer(cstr_ptr))
-	success := C.JS_SetPropertyStr(obj.ctx.ref, obj.ref, cstr_ptr, val.ref)
+	success := C.JS_SetPropertyStr(obj.cctx(), obj.ref, cstr_ptr, val.transfer())
 	// success is either `-1` (exception), `0` (false), or `1` (true).
 	if success < 0 {
 		panic(fmt.Sprintf(`[Object.Set]: setting the value of the property "%s" resulted in an exception.`, prop))
 	}
 }
 
+// define a javascript `Object`'s own property `prop` with the given property `flags` (a combination of `C.JS_PROP_*` bit-flags).
+//
+// unlike [Value.Set], this does not invoke setters, and it can create non-enumerable or non-writable properties.
+//
+// @ownership-transfer
+func (obj *Value) define(prop string, val *Value, flags C.int) {
+	cstr_ptr := C.CString(prop)
+	defer C.free(unsafe.Pointer(cstr_ptr))
+	success := C.JS_DefinePropertyValueStr(obj.cctx(), obj.ref, cstr_ptr, val.transfer(), flags)
+	// success is either `-1` (exception), `0` (false), or `1` (true).
+	if success < 0 {
+		panic(fmt.Sprintf(`[Object.define]: defining the property "%s" resulted in an exception.`, prop))
+	}
+}
+
+// define a javascript `Object`'s own accessor property `prop` with the given `getter` and `setter` functions,
+// and the given property `flags` (a combination of `C.JS_PROP_*` bit-flags).
+// either of the `getter` or the `setter` may be `nil`, in which case it will be left `undefined`.
+//
+// @ownership-transfer
+func (obj *Value) defineGetSet(prop string, getter *Value, setter *Value, flags C.int) {
+	getter_ref, setter_ref := C.JSValue(C.JS_UNDEFINED), C.JSValue(C.JS_UNDEFINED)
+	if getter != nil {
+		getter_ref = getter.transfer()
+	}
+	if setter != nil {
+		setter_ref = setter.transfer()
+	}
+	prop_atom := obj.ctx.NewAtom(prop)
+	defer prop_atom.Free()
+	success := C.JS_DefinePropertyGetSet(obj.cctx(), obj.ref, prop_atom.ref, getter_ref, setter_ref, flags)
+	// success is either `-1` (exception), `0` (false), or `1` (true).
+	if success < 0 {
+		panic(fmt.Sprintf(`[Object.defineGetSet]: defining the accessor property "%s" resulted in an exception.`, prop))
+	}
+}
+
 // get the value of a javascript `Object`'s property `prop`.
 //
 // quickjs increments the reference count of the returned [Value] whenever it is acquired this way.
@@ -62,7 +99,7 @@ func (obj *Value) Set(prop string, val *Value) {
 func (obj *Value) Get(prop string) *Value {
 	cstr_ptr := C.CString(prop)
 	defer C.free(unsafe.Pointer(cstr_ptr))
-	return &Value{ctx: obj.ctx, ref: C.JS_GetPropertyStr(obj.ctx.ref, obj.ref, cstr_ptr)}
+	return obj.ctx.newValue(C.JS_GetPropertyStr(obj.cctx(), obj.ref, cstr_ptr))
 }
 
 // dictates whether or not an object has a certain value property `prop`.
@@ -92,7 +129,7 @@ func (obj *Value) Delete(prop string) bool {
 //
 // @ownership-transfer
 func (obj *Value) SetIdx(idx int64, val *Value) {
-	success := C.JS_SetPropertyInt64(obj.ctx.ref, obj.ref, C.int64_t(idx), val.ref)
+	success := C.JS_SetPropertyInt64(obj.cctx(), obj.ref, C.int64_t(idx), val.transfer())
 	// success is either `-1` (exception), `0` (false), or `1` (true).
 	if success < 0 {
 		panic(fmt.Sprintf(`[Object.Set]: setting the value of the numeric index "%d" resulted in an exception.`, idx))
@@ -115,7 +152,7 @@ func (obj *Value) GetIdx(idx int64) *Value {
 	// here, we recreate the inner logic of `JS_GetPropertyInt64` since it is not an exported function.
 	if (idx >= 0) && (idx <= max_int32) {
 		ctx := obj.ctx
-		return &Value{ctx: ctx, ref: C.JS_GetPropertyUint32(obj.ctx.ref, obj.ref, C.uint32_t(idx))}
+		return ctx.newValue(C.JS_GetPropertyUint32(obj.cctx(), obj.ref, C.uint32_t(idx)))
 	}
 	panic("[Value.GetIdx]: TODO ISSUE: negative indexes and those greater than `uint32` have not been implemented due to the inavailability of `JS_NewAtomInt64` and/or `JS_GetPropertyInt64` in the header file.")
 	// atom_prop := C.JSAtom{}
@@ -154,14 +191,12 @@ func (obj *Value) CallMethod(method_name string, args ...*Value) *Value {
 	return js_fn.Call(obj, args...)
 }
 
-// TODO implement typeof, either here or inside `./value.go`.
-
 // check if an object `obj` is an instance of a class constructor `cls`.
 func (obj *Value) IsInstanceOf(cls *Value) bool {
 	if obj == nil || cls == nil || cls.IsUndefined() {
 		return false
 	}
-	success := C.JS_IsInstanceOf(obj.ctx.ref, obj.ref, cls.ref)
+	success := C.JS_IsInstanceOf(obj.cctx(), obj.ref, cls.ref)
 	// success is either `-1` (exception), `0` (false), or `1` (true).
 	if success >= 0 {
 		return success == 1
@@ -171,8 +206,8 @@ func (obj *Value) IsInstanceOf(cls *Value) bool {
 
 // get the prototype object of a javascript object (analogous to the `Object.getPrototypeOf(obj)` static function).
 func (obj *Value) GetPrototypeOf() *Value {
-	ref := C.JS_GetPrototype(obj.ctx.ref, obj.ref)
-	return &Value{ctx: obj.ctx, ref: ref}
+	ref := C.JS_GetPrototype(obj.cctx(), obj.ref)
+	return obj.ctx.newValue(ref)
 }
 
 // set the prototype of a javascript object. (analogous to the `Object.setPrototypeOf(obj, proto)` static function)
@@ -182,7 +217,7 @@ func (obj *Value) GetPrototypeOf() *Value {
 //
 // note that we don't return back the original `obj` due to the risk that the user might double-free the returned value.
 func (obj *Value) SetPrototypeTo(js_proto *Value) bool {
-	success := C.JS_SetPrototype(obj.ctx.ref, obj.ref, js_proto.ref)
+	success := C.JS_SetPrototype(obj.cctx(), obj.ref, js_proto.ref)
 	// success is either `-1` (exception), `0` (false), or `1` (true).
 	if success >= 0 {
 		return success == 1
@@ -218,7 +253,7 @@ func (obj *Value) GetOwnProperties(flags GetOwnProperties_Flag) []*Atom {
 	var first_result_ptr *C.JSPropertyEnum
 	var size C.uint32_t
 	// success is `-1` if an exception occurs (such as `obj` not actually being an `Object` type), or `0` when successful
-	success := C.JS_GetOwnPropertyNames(ctx.ref, &first_result_ptr, &size, obj.ref, C.int(flags))
+	success := C.JS_GetOwnPropertyNames(obj.cctx(), &first_result_ptr, &size, obj.ref, C.int(flags))
 	if success < 0 {
 		panic(`[Value.GetOwnProperties]: the provided value is not of "Object" type.`)
 	}
@@ -0,0 +1,302 @@
+// this file contains the [Pool], which executes jobs from many goroutines in parallel,
+// on a fixed number of pre-warmed runtime and context pairs (each of which is pinned to its own locked os-thread, see [NewLockedRuntime]).
+//
+// every runtime of the pool is initialized identically (see [PoolOptions]), so a job may land on any of them.
+// since jobs share their runtime with the jobs that ran before them, a runtime is recycled (i.e. replaced by a freshly initialized one)
+// after a certain number of jobs, after it runs out of memory, or after a job panics, so that leftover state does not pile up.
+
+package bridge
+
+import (
+	context "context"
+	errors "errors"
+	fmt "fmt"
+	runtime "runtime"
+	sync "sync"
+	sync_atomic "sync/atomic"
+	time "time"
+)
+
+// returned by [Pool.Do] once the pool has been closed.
+var ErrPoolClosed = errors.New("the pool has been closed")
+
+// the configuration of a [Pool].
+type PoolOptions struct {
+	// the number of runtimes (and therefore, the number of jobs that may run in parallel).
+	// it defaults to `runtime.GOMAXPROCS(0)`.
+	Size int
+	// the go-functions that get bound to the `globalThis` of every context, keyed by their names.
+	Functions map[string]GoFunction
+	// the compiled scripts or es-modules (see [Context.Compile]) that get evaluated in every context, before the `InitScripts`.
+	InitBytecode [][]byte
+	// the scripts that get evaluated in every context, in order.
+	InitScripts []string
+	// an optional function that gets called last, for any other initialization of the context.
+	Init func(ctx *Context) error
+	// the number of jobs after which a runtime gets recycled, or `0` to never recycle based on the job count.
+	MaxJobs int
+	// the memory limit of every runtime (see [Runtime.SetMemoryLimit]), or `0` for no limit.
+	// a runtime is recycled once it runs out of memory during a job, which is decided from the runtime's own record of failed allocations
+	// (see `./limits.go`), so it happens even if the job swallows the error (or returns an unrelated one).
+	MemoryLimit int
+}
+
+// a snapshot of the statistics of a [Pool] (see [Pool.Stats]).
+type PoolStats struct {
+	Size        int           // the number of runtimes in the pool.
+	QueueDepth  int           // the number of jobs waiting for a free runtime.
+	Running     int           // the number of jobs being executed.
+	Completed   int64         // the number of executed jobs, including the failed ones.
+	Failed      int64         // the number of executed jobs that returned an error (or panicked).
+	Recycled    int64         // the number of times that a runtime has been recycled.
+	AverageWait time.Duration // the average time that the executed jobs spent in the queue.
+	MaxWait     time.Duration // the longest time that an executed job spent in the queue.
+	AverageRun  time.Duration // the average execution time of the executed jobs.
+}
+
+// a pool of pre-warmed runtimes, which execute jobs submitted from any goroutine (see [Pool.Do]).
+type Pool struct {
+	options PoolOptions
+	jobs    chan *poolJob
+	// closed by [Pool.Close], which stops the workers.
+	closed    chan struct{}
+	closeOnce sync.Once
+	workers   sync.WaitGroup
+	stats     poolCounters
+}
+
+// the live counters behind [PoolStats], which are updated by the workers and the submitting goroutines alike.
+type poolCounters struct {
+	queued    sync_atomic.Int64
+	running   sync_atomic.Int64
+	completed sync_atomic.Int64
+	failed    sync_atomic.Int64
+	recycled  sync_atomic.Int64
+	// the sums of the durations (in nanoseconds) of all executed jobs.
+	totalWait sync_atomic.Int64
+	totalRun  sync_atomic.Int64
+	maxWait   sync_atomic.Int64
+}
+
+type poolJob struct {
+	goctx  context.Context
+	fn     func(ctx *Context) error
+	queued time.Time
+	result chan error
+}
+
+// create a new pool, and initialize all of its runtimes up front, so that the first jobs do not pay for their creation.
+// if any of the runtimes fails to initialize (for instance, due to an exception in one of the `InitScripts`), then the error is returned.
+//
+// example:
+//
+//	pool, err := bridge.NewPool(bridge.PoolOptions{
+//		InitScripts: []string{`function double(x) { return x * 2 }`},
+//		MaxJobs:     1000,
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	defer pool.Close()
+//	err = pool.Do(goctx, func(ctx *bridge.Context) error {
+//		result, err := ctx.Eval(`double(21)`)
+//		if err != nil {
+//			return err
+//		}
+//		defer result.Free()
+//		fmt.Println(result.ToInt64()) // 42
+//		return nil
+//	})
+func NewPool(options PoolOptions) (*Pool, error) {
+	if options.Size <= 0 {
+		options.Size = runtime.GOMAXPROCS(0)
+	}
+	pool := &Pool{
+		options: options,
+		jobs:    make(chan *poolJob),
+		closed:  make(chan struct{}),
+	}
+	runtimes := make([]*Runtime, 0, options.Size)
+	for range options.Size {
+		rt, err := pool.newRuntime()
+		if err != nil {
+			for _, rt := range runtimes {
+				rt.Free()
+			}
+			return nil, err
+		}
+		runtimes = append(runtimes, rt)
+	}
+	pool.workers.Add(len(runtimes))
+	for _, rt := range runtimes {
+		go pool.work(rt)
+	}
+	return pool, nil
+}
+
+// execute `fn` on the context of the first runtime to become available, and wait for it to return.
+// this method is safe to call from any goroutine.
+//
+// the values created inside of `fn` must not escape it, since the runtime moves on to other jobs afterwards.
+// the job is interrupted once `goctx` is cancelled or its deadline is exceeded, just like with [Context.EvalContext],
+// and if `goctx` ends while the job is still waiting in the queue, then its error is returned without executing `fn`.
+// a panic inside of `fn` is returned as a [*PanicError].
+func (pool *Pool) Do(goctx context.Context, fn func(ctx *Context) error) error {
+	job := &poolJob{goctx: goctx, fn: fn, queued: time.Now(), result: make(chan error, 1)}
+	pool.stats.queued.Add(1)
+	select {
+	case pool.jobs <- job:
+	case <-goctx.Done():
+		pool.stats.queued.Add(-1)
+		return goctx.Err()
+	case <-pool.closed:
+		pool.stats.queued.Add(-1)
+		return ErrPoolClosed
+	}
+	return <-job.result
+}
+
+// get a snapshot of the pool's statistics.
+func (pool *Pool) Stats() PoolStats {
+	stats := &pool.stats
+	completed := stats.completed.Load()
+	average := func(total int64) time.Duration {
+		if completed == 0 {
+			return 0
+		}
+		return time.Duration(total / completed)
+	}
+	return PoolStats{
+		Size:        pool.options.Size,
+		QueueDepth:  int(stats.queued.Load()),
+		Running:     int(stats.running.Load()),
+		Completed:   completed,
+		Failed:      stats.failed.Load(),
+		Recycled:    stats.recycled.Load(),
+		AverageWait: average(stats.totalWait.Load()),
+		MaxWait:     time.Duration(stats.maxWait.Load()),
+		AverageRun:  average(stats.totalRun.Load()),
+	}
+}
+
+// close the pool, after waiting for the running jobs to finish, and free all of its runtimes.
+// the jobs that are still waiting in the queue return [ErrPoolClosed].
+func (pool *Pool) Close() {
+	pool.closeOnce.Do(func() { close(pool.closed) })
+	pool.workers.Wait()
+}
+
+// create and initialize a runtime of the pool, according to its [PoolOptions].
+func (pool *Pool) newRuntime() (*Runtime, error) {
+	options := &pool.options
+	rt := NewLockedRuntime()
+	if rt == nil {
+		return nil, errors.New("[Pool]: failed to create a runtime")
+	}
+	err := rt.Do(func(ctx *Context) error {
+		if options.MemoryLimit > 0 {
+			rt.SetMemoryLimit(options.MemoryLimit)
+		}
+		global := ctx.GetGlobalThis()
+		for name, fn := range options.Functions {
+			global.Set(name, ctx.NewFunction(name, 0, fn))
+		}
+		for _, bytecode := range options.InitBytecode {
+			result, err := ctx.EvalBytecode(bytecode)
+			if err != nil {
+				return err
+			}
+			result.Free()
+		}
+		for _, code := range options.InitScripts {
+			result, err := ctx.Eval(code)
+			if err != nil {
+				return err
+			}
+			result.Free()
+		}
+		if options.Init != nil {
+			return options.Init(ctx)
+		}
+		return nil
+	})
+	if err != nil {
+		rt.Free()
+		return nil, fmt.Errorf("[Pool]: failed to initialize a runtime: %w", err)
+	}
+	return rt, nil
+}
+
+// execute the queued jobs on the runtime `rt`, recycling it whenever needed, until the pool is closed.
+func (pool *Pool) work(rt *Runtime) {
+	defer pool.workers.Done()
+	stats := &pool.stats
+	jobs := 0
+	for {
+		var job *poolJob
+		select {
+		case job = <-pool.jobs:
+		case <-pool.closed:
+			if rt != nil {
+				rt.Free()
+			}
+			return
+		}
+		stats.queued.Add(-1)
+		wait := int64(time.Since(job.queued))
+		// a previous recycling may have failed, in which case the runtime is created anew for this job.
+		if rt == nil {
+			var err error
+			if rt, err = pool.newRuntime(); err != nil {
+				job.result <- err
+				continue
+			}
+		}
+		stats.running.Add(1)
+		started := time.Now()
+		out_of_memory := rt.limits.outOfMemoryCount()
+		err := pool.run(rt, job)
+		stats.totalRun.Add(int64(time.Since(started)))
+		stats.running.Add(-1)
+		stats.totalWait.Add(wait)
+		for max_wait := stats.maxWait.Load(); wait > max_wait && !stats.maxWait.CompareAndSwap(max_wait, wait); {
+			max_wait = stats.maxWait.Load()
+		}
+		if err != nil {
+			stats.failed.Add(1)
+		}
+		stats.completed.Add(1)
+		job.result <- err
+
+		jobs++
+		var panic_err *PanicError
+		ran_out_of_memory := rt.limits.outOfMemoryCount() != out_of_memory
+		if (pool.options.MaxJobs > 0 && jobs >= pool.options.MaxJobs) || ran_out_of_memory || errors.As(err, &panic_err) {
+			rt.Free()
+			// if the replacement fails, then it is retried by the next job, which receives the error instead.
+			rt, _ = pool.newRuntime()
+			jobs = 0
+			stats.recycled.Add(1)
+		}
+	}
+}
+
+// execute a single job on the runtime `rt`, with the job's go context bound to the runtime (see [Runtime.bindContext]).
+func (pool *Pool) run(rt *Runtime, job *poolJob) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			panic_err, ok := recovered.(*PanicError)
+			if !ok {
+				panic_err = newPanicError(recovered)
+			}
+			err = panic_err
+		}
+	}()
+	return rt.Do(func(ctx *Context) error {
+		if err := job.goctx.Err(); err != nil {
+			return err
+		}
+		defer rt.bindContext(job.goctx)()
+		return job.fn(ctx)
+	})
+}
@@ -54,6 +54,6 @@ func (val *Value) PrintString() string {
 	defer buf_handle.Delete()
 	// setting the `options` parameter to `nil` gives us the default options.
 	// TODO: in the future, attach the printing `options` to your `ctx`, and then pass it here.
-	C.JS_PrintValue(val.ctx.ref, &C.printValueWriteFn, unsafe.Pointer(buf_handle), val.ref, nil)
+	C.JS_PrintValue(val.cctx(), &C.printValueWriteFn, unsafe.Pointer(buf_handle), val.ref, nil)
 	return buf_ptr.String()
 }
@@ -0,0 +1,110 @@
+// this file contains the creation of javascript promises from go, and the inspection of their state.
+
+package bridge
+
+/*
+#include "./include0_quickjs.h"
+*/
+import "C"
+
+type PromiseStateEnum int // super set of `C.JSPromiseStateEnum`
+
+const (
+	PromiseInvalid   PromiseStateEnum = -1 // the value is not a promise.
+	PromisePending   PromiseStateEnum = C.JS_PROMISE_PENDING
+	PromiseFulfilled PromiseStateEnum = C.JS_PROMISE_FULFILLED
+	PromiseRejected  PromiseStateEnum = C.JS_PROMISE_REJECTED
+)
+
+// the resolving functions of a promise created via [Context.NewPromise], which are released once the promise settles.
+type pendingPromise struct {
+	ctx     *Context
+	resolve *Value
+	reject  *Value
+}
+
+func (pending *pendingPromise) free() {
+	pending.resolve.Free()
+	pending.reject.Free()
+	delete(pending.ctx.pendingPromises, pending)
+}
+
+// check if the promise can no longer be settled, either because it has already been settled, or because its context has been freed.
+func (pending *pendingPromise) done() bool {
+	_, ok := pending.ctx.pendingPromises[pending]
+	return !ok || pending.ctx.ref == nil
+}
+
+// settle the promise by calling one of its resolving functions with the given `arg`.
+// the caller must make sure that the promise is not [pendingPromise.done] yet.
+func (pending *pendingPromise) settle(resolving_fn *Value, arg *Value) {
+	result := resolving_fn.Call(nil, arg)
+	result.Free()
+	pending.free()
+}
+
+// create a new pending javascript `Promise`, along with the go-functions that settle it.
+//
+// this is intended for surfacing the results of asynchronous go-work (such as file IO) to javascript:
+//   - `resolve(val)` fulfills the promise with `val`, while taking ownership of it (just like [Value.Set]). a `nil` value resolves to `undefined`.
+//   - `reject(err)` rejects the promise with a javascript `Error` that carries the message of `err`.
+//
+// only the first call to either of the two functions has an effect, and the subsequent calls are ignored.
+// calls that arrive after the context has been freed are ignored as well (and the value passed to such a late `resolve` is left alone,
+// since it can no longer be freed).
+//
+// > [!important]
+// > just like every other javascript operation, `resolve` and `reject` must be called on the thread that runs the context.
+// > in order to settle a promise from a different goroutine, post a task to the runtime's event loop.
+//
+// @should-free (the `promise`)
+func (ctx *Context) NewPromise() (promise *Value, resolve func(*Value), reject func(error)) {
+	var resolving_funcs [2]C.JSValue
+	promise = ctx.newValue(C.JS_NewPromiseCapability(ctx.ref, &resolving_funcs[0]))
+	// the resolving functions must survive until the promise settles, regardless of any [Scope] that may be active right now.
+	pending := &pendingPromise{
+		ctx:     ctx,
+		resolve: ctx.newValue(resolving_funcs[0]).Detach(),
+		reject:  ctx.newValue(resolving_funcs[1]).Detach(),
+	}
+	ctx.pendingPromises[pending] = struct{}{}
+	resolve = func(val *Value) {
+		if ctx.ref == nil {
+			return
+		}
+		if val == nil {
+			val = ctx.NewUndefined()
+		}
+		defer val.Free()
+		if !pending.done() {
+			pending.settle(pending.resolve, val)
+		}
+	}
+	reject = func(err error) {
+		if pending.done() {
+			return
+		}
+		js_err := ctx.NewError(err)
+		defer js_err.Free()
+		pending.settle(pending.reject, js_err)
+	}
+	return promise, resolve, reject
+}
+
+// test if your value is an instance of a `Promise`.
+func (val *Value) IsPromise() bool {
+	return val.IsInstanceOf(val.ctx.valueCache.promise)
+}
+
+// get the state of a promise, or [PromiseInvalid] if the value is not a promise.
+func (val *Value) PromiseState() PromiseStateEnum {
+	return PromiseStateEnum(C.JS_PromiseState(val.cctx(), val.ref))
+}
+
+// get the fulfillment value (or the rejection reason) of a settled promise.
+// if the promise is still pending, `undefined` is returned.
+//
+// @should-free
+func (val *Value) PromiseResult() *Value {
+	return val.ctx.newValue(C.JS_PromiseResult(val.cctx(), val.ref))
+}
@@ -5,28 +5,81 @@ package bridge
 */
 import "C"
 import (
-	runtime "runtime"
+	cgo "runtime/cgo"
+	sync_atomic "sync/atomic"
 )
 
 type Runtime struct {
 	ref *C.JSRuntime
+	// the go-backed classes registered to this runtime via [Runtime.NewClass].
+	classes map[C.JSClassID]*Class
+	// the resolver and loader of imported es-modules, set via [Runtime.SetModuleLoader].
+	moduleLoader ModuleLoader
+	// the state of the runtime's event loop (see [Runtime.RunLoop]).
+	loop *eventLoop
+	// the hidden class whose instances carry the original go `error` behind a javascript `Error` (see [Context.NewError]).
+	goErrorClass *Class
+	// the hidden class whose instances carry the go-side of the functions created via [Context.NewFunction].
+	goFunctionClass *Class
+	// the runtime-side record of the resource limits that the runtime has run into (see `./limits.go`).
+	limits *limitRecord
+	// the state of the runtime's interrupt handler (see [Runtime.Interrupt]).
+	interrupt *interruptState
+	// the handle to this very runtime, which is passed as the opaque data of the runtime-wide quickjs callbacks.
+	handle cgo.Handle
+	// the hidden class whose instances carry the go-side of the workers spawned by javascript (see [Context.RegisterWorkers]).
+	workerClass *Class
+	// the executor that the runtime is pinned to (see [NewLockedRuntime]), or `nil` if the runtime may be used from any goroutine.
+	exec *executor
+	// the number of nested executions of javascript code that are currently in progress (see [Runtime.enter]).
+	depth int
+	// the id of the goroutine that is executing javascript code in the runtime, or `0` while it is idle.
+	// this is only recorded in debug builds, for runtimes created via [NewRuntime] (see [Runtime.assertOwner]).
+	executing sync_atomic.Int64
+	// the number of unknown shared memory blocks that quickjs has tried to reference during the latest [Context.DeserializeWithOptions] (see `./shared.go`).
+	unknownSharedBlocks int
 }
 
 func NewRuntime() *Runtime {
-	rt := &Runtime{
-		ref: C.JS_NewRuntime(),
-	}
-	if rt.ref == nil {
+	ref, limits := newLimitedRuntime()
+	if ref == nil {
 		return nil
 	}
-	// TODO: I'm unsure if we should be cleaning it up automatically, or if it should be the end user's responsibility.
-	runtime.AddCleanup(rt, (*Runtime).Free, nil)
+	rt := &Runtime{
+		ref:     ref,
+		classes: map[C.JSClassID]*Class{},
+		limits:  limits,
+	}
+	rt.handle = cgo.NewHandle(rt)
+	rt.initEventLoop()
+	rt.initInterruptHandler()
+	rt.initSharedArrayBuffers()
+	rt.goErrorClass = rt.NewClass(ClassDefinition{Name: "GoError"})
+	rt.goFunctionClass = rt.NewClass(ClassDefinition{Name: "GoFunction"})
 	return rt
 }
 
+// free the runtime, which must be done after all of its contexts have been freed.
+//
+// runtimes are never freed automatically by go's garbage collector, since quickjs must not be called from the collector's goroutine
+// (and the runtime's own [cgo.Handle] keeps it reachable anyway).
+// for a runtime created via [NewLockedRuntime], the call is marshalled onto its executor, which stops once the runtime is freed.
 func (rt *Runtime) Free() {
+	if rt.exec != nil && !rt.exec.isCurrent() {
+		rt.exec.run(func() error { rt.Free(); return nil })
+		return
+	}
 	if rt.ref != nil {
+		rt.assertOwner()
+		if rt.exec != nil {
+			rt.exec.defaultContext.Free()
+		}
 		C.JS_FreeRuntime(rt.ref)
 		rt.ref = nil
+		rt.limits.free()
+		rt.handle.Delete()
+		if rt.exec != nil {
+			rt.exec.stop()
+		}
 	}
 }
@@ -0,0 +1,132 @@
+// this file contains [Scope]s, which free every [Value] and [Atom] created within them once they exit,
+// thereby sparing you from having to pair each `Get`, `Call`, etc... with a `defer x.Free()`.
+//
+// each [Context] has a stack of active scopes, and every go-owned value (or atom) that gets created while a scope is active is recorded by the innermost scope.
+// a recorded value stops belonging to its scope once it gets freed explicitly, or once its ownership is transferred (such as via [Value.Set]),
+// so mixing scopes with manual freeing is safe.
+//
+// while go code runs on behalf of javascript (such as a [GoFunction], or a class constructor), the active scopes are set aside,
+// since the values created by the callback belong to it, rather than to whichever scope happened to be active when javascript was entered.
+//
+// the context itself also has a scope of its own, which exits when the context is freed.
+// this is what [Value.FreeOnExit] and [Atom.FreeOnExit] use under the hood, by moving the value over to the context's scope.
+
+package bridge
+
+// a set of values and atoms that get freed together, once the scope exits (see [Context.WithScope]).
+type Scope struct {
+	ctx *Context
+	// the enclosing scope, which receives the escaped values (see [Scope.Escape]), or `nil` for a top-level scope.
+	parent *Scope
+	// the values and atoms recorded by this scope. the ones that have since left the scope are skipped upon exit,
+	// which can be identified by their `scope` field no longer pointing to this scope.
+	values []*Value
+	atoms  []*Atom
+}
+
+// run `fn` inside of a new [Scope], which frees every [Value] and [Atom] created within it after `fn` returns (or panics).
+// values that must outlive the scope have to be passed to [Scope.Escape].
+//
+// scopes can be nested, in which case a value belongs to the innermost scope that was active when it was created.
+// the error returned by `fn` is returned as is.
+//
+// example:
+//
+//	var total int64
+//	err := ctx.WithScope(func(s *bridge.Scope) error {
+//		items, err := ctx.Eval(`[1, 2, 3]`)
+//		if err != nil {
+//			return err
+//		}
+//		for i := range items.Get("length").ToInt64() {
+//			total += items.GetIdx(i).ToInt64()
+//		}
+//		return nil
+//	}) // `items`, its `length`, and all of its elements are freed over here.
+func (ctx *Context) WithScope(fn func(s *Scope) error) error {
+	s := &Scope{ctx: ctx, parent: ctx.scope}
+	ctx.scope = s
+	defer func() {
+		ctx.scope = s.parent
+		s.exit()
+	}()
+	return fn(s)
+}
+
+// get the context of the scope.
+func (s *Scope) GetContext() *Context {
+	return s.ctx
+}
+
+// promote the value `val` to the enclosing scope, so that it is not freed when this scope exits.
+// if this is a top-level scope, then the ownership of the value is passed to the caller of [Context.WithScope], who must free it.
+//
+// the same `val` is returned, for convenience.
+func (s *Scope) Escape(val *Value) *Value {
+	if val == nil || val.scope != s {
+		return val
+	}
+	val.scope = nil
+	if s.parent != nil {
+		s.parent.addValue(val)
+	}
+	return val
+}
+
+// same as [Scope.Escape], but for atoms.
+func (s *Scope) EscapeAtom(atom *Atom) *Atom {
+	if atom == nil || atom.scope != s {
+		return atom
+	}
+	atom.scope = nil
+	if s.parent != nil {
+		s.parent.addAtom(atom)
+	}
+	return atom
+}
+
+// record the value in this scope, removing it from its previous scope.
+func (s *Scope) addValue(val *Value) {
+	val.scope = s
+	s.values = append(s.values, val)
+}
+
+// record the atom in this scope, removing it from its previous scope.
+func (s *Scope) addAtom(atom *Atom) {
+	atom.scope = s
+	s.atoms = append(s.atoms, atom)
+}
+
+// free all values and atoms that still belong to this scope.
+func (s *Scope) exit() {
+	for _, val := range s.values {
+		if val.scope == s {
+			val.Free()
+		}
+	}
+	for _, atom := range s.atoms {
+		if atom.scope == s {
+			atom.Free()
+		}
+	}
+	s.values, s.atoms = nil, nil
+}
+
+// detach the value from its scope (if any), so that it does not get freed when the scope exits, and its ownership passes to the caller, who must free it.
+// unlike [Scope.Escape], which only promotes the value to the enclosing scope, this lets a value outlive every active scope
+// (such as a duplicated argument that is kept by go code for later).
+//
+// the same `val` is returned, for convenience.
+func (val *Value) Detach() *Value {
+	if val != nil {
+		val.scope = nil
+	}
+	return val
+}
+
+// set aside the active scopes of the context while go code runs on behalf of javascript, and return the function that restores them.
+func (ctx *Context) suspendScopes() (restore func()) {
+	scope := ctx.scope
+	ctx.scope = nil
+	return func() { ctx.scope = scope }
+}
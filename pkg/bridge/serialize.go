@@ -0,0 +1,348 @@
+// this file contains the serialization of javascript object graphs to bytes (and back), based on quickjs's `JS_WriteObject2` and `JS_ReadObject`,
+// so that plain data can be persisted to disk, or transferred between contexts, runtimes, and processes.
+//
+// quickjs can natively serialize primitives (except for symbols), plain objects, arrays, `Date`s, `ArrayBuffer`s, typed arrays,
+// and the boxed primitives (such as `new String("...")`). functions, symbols, class instances with internal slots (such as `Error`s), `WeakMap`s, etc... cannot be serialized.
+// since this version of quickjs lacks support for `Map` and `Set`, we replace them with plain placeholder objects before serialization,
+// and then reconstruct them after deserialization (see [serializeCodecSource]).
+//
+// just like our bytecode, serialized values are prefixed with a versioned header (see `./bytecode.go`), where the kind byte holds the [serializeFlag]s.
+
+package bridge
+
+/*
+#include "./include1_helpers.h"
+*/
+import "C"
+import (
+	errors "errors"
+	fmt "fmt"
+	unsafe "unsafe"
+)
+
+// the magic string at the beginning of every serialized value produced by [Value.Serialize].
+const serializeMagic = "qjso"
+
+// the flags stored in the header of serialized values, describing how they must be read.
+type serializeFlag = byte
+
+const (
+	serializeFlagReferences         serializeFlag = 1 << 0
+	serializeFlagSharedArrayBuffers serializeFlag = 1 << 1
+	serializeFlagCollections        serializeFlag = 1 << 2 // the value is an envelope of the encoded `Map`s and `Set`s.
+	serializeFlagAll                serializeFlag = serializeFlagReferences | serializeFlagSharedArrayBuffers | serializeFlagCollections
+)
+
+// configures the serialization of [Value.Serialize] and [Value.SerializeShared].
+type SerializeOptions struct {
+	// preserve the identity of objects that are referenced multiple times, thereby also permitting cyclic references.
+	// when disabled, an object that is referenced twice is deserialized as two distinct copies, and cyclic references fail to serialize.
+	//
+	// note that this mode is always enabled when the value contains a `Map` or a `Set` (which quickjs cannot serialize on its own).
+	References bool
+}
+
+// configures the deserialization of [Context.DeserializeWithOptions].
+type DeserializeOptions struct {
+	// accept data produced by [Value.SerializeShared], whose `SharedArrayBuffer`s are rebuilt from the raw memory addresses embedded in it.
+	// when disabled, such data is rejected with an [ErrSharedArrayBuffers] error.
+	//
+	// > [!caution]
+	// > only enable this for data that was produced by [Value.SerializeShared] within the same process, and whose [SharedRefs] are still held.
+	// > never enable it for data that comes from an untrusted source.
+	SharedArrayBuffers bool
+}
+
+// returned by [Context.DeserializeWithOptions] when the data references `SharedArrayBuffer`s, but [DeserializeOptions.SharedArrayBuffers] is disabled,
+// or when one of the referenced shared memory blocks does not exist (anymore).
+var ErrSharedArrayBuffers = errors.New("invalid shared array buffer reference")
+
+// the references to the shared memory blocks (see `./shared.go`) of the `SharedArrayBuffer`s inside of data produced by [Value.SerializeShared],
+// which keep the blocks alive (even if the original `SharedArrayBuffer`s get freed) until [SharedRefs.Release] is called.
+// they must be held until the data has been deserialized via [Context.DeserializeWithOptions].
+type SharedRefs struct {
+	blocks []unsafe.Pointer
+}
+
+// release the references to the shared memory blocks. data that references them must not be deserialized afterwards.
+func (refs *SharedRefs) Release() {
+	for _, ptr := range refs.blocks {
+		freeSharedBlock(ptr)
+	}
+	refs.blocks = nil
+}
+
+// serialize the javascript value (and everything that it references) to bytes, which can later be deserialized via [Context.Deserialize].
+// `SharedArrayBuffer`s cannot be serialized this way (see [Value.SerializeShared] for that).
+//
+// see the comment at the top of `./serialize.go` for the types that can be serialized.
+func (val *Value) Serialize(opts SerializeOptions) ([]byte, error) {
+	data, shared, err := val.serialize(opts, false)
+	for _, ptr := range shared {
+		freeSharedBlock(ptr)
+	}
+	return data, err
+}
+
+// same as [Value.Serialize], but `SharedArrayBuffer`s are serialized by reference (i.e. by their memory address),
+// so that the deserialized buffers share their memory with the original ones.
+// the returned [SharedRefs] keep that memory alive, and must be released once the data has been deserialized
+// (which in turn requires [DeserializeOptions.SharedArrayBuffers]).
+//
+// such data is only valid within the same process, and only for as long as its [SharedRefs] are held.
+func (val *Value) SerializeShared(opts SerializeOptions) ([]byte, *SharedRefs, error) {
+	data, shared, err := val.serialize(opts, true)
+	refs := &SharedRefs{blocks: shared}
+	if err != nil {
+		refs.Release()
+		return nil, nil, err
+	}
+	return data, refs, nil
+}
+
+// serialize the value, along with its `SharedArrayBuffer`s when `sharedArrayBuffers` is set, and return the shared memory blocks (see `./shared.go`)
+// referenced by the data, each of which has gained a reference that must be released (via `freeSharedBlock`) once the data has been deserialized.
+// this keeps the shared buffers alive while the data is in transit, even if the original `SharedArrayBuffer`s get freed in the meantime.
+func (val *Value) serialize(opts SerializeOptions, sharedArrayBuffers bool) (data []byte, shared []unsafe.Pointer, err error) {
+	ctx := val.ctx
+	flags := serializeFlag(0)
+	if opts.References {
+		flags |= serializeFlagReferences
+	}
+	if sharedArrayBuffers {
+		flags |= serializeFlagSharedArrayBuffers
+	}
+	data, shared, err = ctx.writeObject(val, flags)
+	if err == nil {
+		return data, shared, nil
+	}
+	// quickjs fails on `Map`s and `Set`s, so only then do we walk the value's graph to replace them with placeholders (see [serializeCodecSource]).
+	for _, ptr := range shared {
+		freeSharedBlock(ptr)
+	}
+	encoded := ctx.valueCache.serializeCodec.CallMethod("encode", val)
+	if encoded.IsException() {
+		return nil, nil, ctx.takeException()
+	}
+	defer encoded.Free()
+	if encoded.IsUndefined() {
+		// the value contains no collections, so the failure had nothing to do with them.
+		return nil, nil, err
+	}
+	// the placeholders of the encoded `Map`s and `Set`s can only be recognized when their identity is preserved.
+	return ctx.writeObject(encoded, flags|serializeFlagCollections|serializeFlagReferences)
+}
+
+// write the `target` value via `JS_WriteObject2` with the given flags, and prefix the result with our versioned header.
+// see [Value.serialize] for the returned shared memory blocks.
+func (ctx *Context) writeObject(target *Value, flags serializeFlag) (data []byte, shared []unsafe.Pointer, err error) {
+	c_flags := C.int(0)
+	if flags&serializeFlagReferences != 0 {
+		c_flags |= C.JS_WRITE_OBJ_REFERENCE
+	}
+	if flags&serializeFlagSharedArrayBuffers != 0 {
+		c_flags |= C.JS_WRITE_OBJ_SAB
+	}
+	var (
+		c_size        C.size_t
+		c_sab_tab     **C.uint8_t
+		c_sab_tab_len C.size_t
+	)
+	c_buf := C.JS_WriteObject2(ctx.ref, &c_size, target.ref, c_flags, &c_sab_tab, &c_sab_tab_len)
+	if c_sab_tab != nil {
+		// the table lists the shared buffers referenced by the data (whose addresses are embedded in it), which we keep alive until it is deserialized.
+		for _, ptr := range unsafe.Slice(c_sab_tab, int(c_sab_tab_len)) {
+			if _, ok := dupSharedBlock(unsafe.Pointer(ptr)); ok {
+				shared = append(shared, unsafe.Pointer(ptr))
+			}
+		}
+		C.js_free(ctx.ref, unsafe.Pointer(c_sab_tab))
+	}
+	if c_buf == nil {
+		return nil, shared, ctx.takeException()
+	}
+	defer C.js_free(ctx.ref, unsafe.Pointer(c_buf))
+
+	header := newVersionHeader(serializeMagic, flags)
+	data = make([]byte, len(header), len(header)+int(c_size))
+	copy(data, header)
+	return append(data, unsafe.Slice((*byte)(unsafe.Pointer(c_buf)), int(c_size))...), shared, nil
+}
+
+// deserialize the bytes produced by [Value.Serialize] back into a javascript value.
+//
+// if the data was produced by a different quickjs version, an [ErrBytecodeVersion] error is returned.
+// if the data was produced by [Value.SerializeShared], it is rejected with an [ErrSharedArrayBuffers] error
+// (use [Context.DeserializeWithOptions] to accept such data).
+//
+// @should-free
+func (ctx *Context) Deserialize(data []byte) (*Value, error) {
+	return ctx.DeserializeWithOptions(data, DeserializeOptions{})
+}
+
+// same as [Context.Deserialize], but configurable via `opts` (see [DeserializeOptions]).
+//
+// @should-free
+func (ctx *Context) DeserializeWithOptions(data []byte, opts DeserializeOptions) (*Value, error) {
+	if ctx.ref == nil {
+		return nil, errors.New("context is nil")
+	}
+	body, flags, err := parseVersionHeader(data, serializeMagic)
+	if err != nil {
+		return nil, err
+	}
+	if flags&^serializeFlagAll != 0 {
+		return nil, fmt.Errorf("%w: unknown serialization flags: %d", ErrBytecodeVersion, flags)
+	}
+	if len(body) == 0 {
+		return nil, fmt.Errorf("%w: the serialized data is empty", ErrBytecodeVersion)
+	}
+	c_flags := C.int(0)
+	if flags&serializeFlagReferences != 0 {
+		c_flags |= C.JS_READ_OBJ_REFERENCE
+	}
+	if flags&serializeFlagSharedArrayBuffers != 0 {
+		if !opts.SharedArrayBuffers {
+			return nil, fmt.Errorf("%w: the data references shared array buffers, which were not permitted", ErrSharedArrayBuffers)
+		}
+		c_flags |= C.JS_READ_OBJ_SAB
+	}
+	rt := ctx.rt
+	rt.unknownSharedBlocks = 0
+	val := ctx.newValue(C.JS_ReadObject(ctx.ref, (*C.uint8_t)(unsafe.Pointer(&body[0])), C.size_t(len(body)), c_flags))
+	if val.IsException() {
+		return nil, ctx.takeException()
+	}
+	if rt.unknownSharedBlocks > 0 {
+		// quickjs cannot be stopped from building the buffers over the unknown addresses, so they are discarded before anything can touch them.
+		val.Free()
+		return nil, fmt.Errorf("%w: the data references %d unknown shared memory blocks", ErrSharedArrayBuffers, rt.unknownSharedBlocks)
+	}
+	if flags&serializeFlagCollections == 0 {
+		return val, nil
+	}
+	defer val.Free()
+	decoded := ctx.valueCache.serializeCodec.CallMethod("decode", val)
+	if decoded.IsException() {
+		return nil, ctx.takeException()
+	}
+	return decoded, nil
+}
+
+// the javascript source of the codec that replaces `Map`s and `Set`s with plain placeholder objects (and vice versa).
+//
+// `encode(value)` returns `undefined` when the value contains no `Map` or `Set`, otherwise it returns an envelope `{ value, collections }`,
+// where `value` is a copy of the original value's graph (with placeholders instead of collections),
+// and `collections` is an array of `[placeholder, is_map, entries]` tuples.
+// since the copy only spans arrays and plain objects (whose prototype is either `Object.prototype` or `null`),
+// collections nested inside of other kinds of objects (such as class instances) are not replaced.
+// `decode(envelope)` reverses the process, by replacing the placeholders (in place) with newly constructed `Map`s and `Set`s.
+//
+// the codec runs inside of the user's context, so it only ever uses the intrinsics that it has captured before any user code could run
+// (see [Context.injectSerializeCodec]), and it avoids everything that consults patchable prototypes
+// (such as iterators, destructuring, `instanceof`, method calls on collections, and assignments to new properties).
+// getters are never invoked, since quickjs does not support them either.
+const serializeCodecSource = `(() => {
+	const
+		apply = Reflect.apply,
+		define_property = Object.defineProperty,
+		get_own_property_descriptor = Object.getOwnPropertyDescriptor,
+		get_prototype_of = Object.getPrototypeOf,
+		has_own = Object.hasOwn,
+		object_keys = Object.keys,
+		is_array = Array.isArray,
+		object_prototype = Object.prototype,
+		ArrayConstructor = Array,
+		MapConstructor = Map,
+		SetConstructor = Set,
+		TypeErrorConstructor = TypeError,
+		map_size = get_own_property_descriptor(Map.prototype, "size").get,
+		map_get = Map.prototype.get,
+		map_set = Map.prototype.set,
+		map_has = Map.prototype.has,
+		map_for_each = Map.prototype.forEach,
+		set_size = get_own_property_descriptor(Set.prototype, "size").get,
+		set_add = Set.prototype.add,
+		set_has = Set.prototype.has,
+		set_for_each = Set.prototype.forEach
+	const define = (object, key, value) => {
+		define_property(object, key, { __proto__: null, value, writable: true, enumerable: true, configurable: true })
+	}
+	const has_brand = (getter, value) => {
+		try { apply(getter, value, []); return true } catch { return false }
+	}
+	const is_plain = (value) => {
+		if (is_array(value)) { return true }
+		const proto = get_prototype_of(value)
+		return proto === object_prototype || proto === null
+	}
+	const encode = (root) => {
+		const copies = new MapConstructor(), collections = []
+		const visit = (value) => {
+			if (typeof value !== "object" || value === null) { return value }
+			if (apply(map_has, copies, [value])) { return apply(map_get, copies, [value]) }
+			if (is_plain(value)) { return copy_properties(value, is_array(value) ? new ArrayConstructor(value.length) : {}) }
+			const is_map = has_brand(map_size, value)
+			if (!is_map && !has_brand(set_size, value)) { return value }
+			const placeholder = {}, entries = []
+			apply(map_set, copies, [value, placeholder])
+			define(collections, collections.length, [placeholder, is_map, entries])
+			if (is_map) {
+				apply(map_for_each, value, [(item, key) => { define(entries, entries.length, [visit(key), visit(item)]) }])
+			} else {
+				apply(set_for_each, value, [(item) => { define(entries, entries.length, visit(item)) }])
+			}
+			return placeholder
+		}
+		const copy_properties = (value, copy) => {
+			apply(map_set, copies, [value, copy])
+			const keys = object_keys(value)
+			for (let i = 0; i < keys.length; i++) {
+				const descriptor = get_own_property_descriptor(value, keys[i])
+				if (!has_own(descriptor, "value")) { throw new TypeErrorConstructor("only value properties are supported") }
+				define(copy, keys[i], visit(descriptor.value))
+			}
+			return copy
+		}
+		const value = visit(root)
+		return collections.length > 0 ? { value, collections } : undefined
+	}
+	const decode = (envelope) => {
+		const collections = envelope.collections, originals = new MapConstructor(), visited = new SetConstructor()
+		for (let i = 0; i < collections.length; i++) {
+			apply(map_set, originals, [collections[i][0], collections[i][1] ? new MapConstructor() : new SetConstructor()])
+		}
+		const visit = (value) => {
+			if (typeof value !== "object" || value === null) { return value }
+			if (apply(map_has, originals, [value])) { return apply(map_get, originals, [value]) }
+			if (apply(set_has, visited, [value]) || !is_plain(value)) { return value }
+			apply(set_add, visited, [value])
+			const keys = object_keys(value)
+			for (let i = 0; i < keys.length; i++) { define(value, keys[i], visit(value[keys[i]])) }
+			return value
+		}
+		for (let i = 0; i < collections.length; i++) {
+			const collection = apply(map_get, originals, [collections[i][0]]), is_map = collections[i][1], entries = collections[i][2]
+			for (let j = 0; j < entries.length; j++) {
+				if (is_map) {
+					apply(map_set, collection, [visit(entries[j][0]), visit(entries[j][1])])
+				} else {
+					apply(set_add, collection, [visit(entries[j])])
+				}
+			}
+		}
+		return visit(envelope.value)
+	}
+	return { encode, decode }
+})()`
+
+// create the `Map`/`Set` codec object (see [serializeCodecSource]).
+// this must happen while the context is still pristine (i.e. before any user code runs), so that the codec captures the genuine intrinsics.
+func (ctx *Context) injectSerializeCodec() {
+	codec, err := ctx.Eval(serializeCodecSource)
+	if err != nil {
+		panic(fmt.Sprintf("[Context.injectSerializeCodec]: failed to create the serialization codec: %s", err.Error()))
+	}
+	codec.FreeOnExit()
+	ctx.valueCache.serializeCodec = codec
+}
@@ -0,0 +1,223 @@
+// this file contains the [SharedBuffer], a reference-counted block of memory that backs `SharedArrayBuffer`s across runtimes,
+// so that the runtimes of different threads (such as workers, see `./worker.go`) and go goroutines can all share the same memory.
+//
+// every [Runtime] hands the allocation of its `SharedArrayBuffer`s over to us (via quickjs's `JS_SetSharedArrayBufferFunctions`),
+// and we keep track of every shared memory block in a process-wide registry, keyed by its address.
+// each `SharedArrayBuffer` object (in any runtime) holds one reference to its block, and so does each go-side [SharedBuffer] handle,
+// and the block is only released once the last of them lets go of it.
+// this is what makes it safe to pass a `SharedArrayBuffer` to another runtime via `postMessage`, or via [Value.SerializeShared].
+//
+// the memory of a block is allocated by c (except for the ones created via [Context.NewArrayBufferShared], which pin go memory instead),
+// so it never moves, and it may be accessed concurrently with the atomic operations of go's `sync/atomic` package (see also `./atomics.go`).
+
+package bridge
+
+/*
+#include <stdlib.h>
+#include "./include1_helpers.h"
+
+// the signatures of the `JSSharedArrayBufferFunctions` callbacks, which quickjs declares inline.
+typedef void* sharedBufferAllocFunc(void *opaque, size_t size);
+typedef void sharedBufferRefFunc(void *opaque, void *ptr);
+
+// forward declarations of the shared-buffer callback functions, otherwise the compiler won't discover them.
+sharedBufferAllocFunc goSharedBufferAlloc;
+sharedBufferRefFunc goSharedBufferFree;
+sharedBufferRefFunc goSharedBufferDup;
+JSFreeArrayBufferDataFunc sharedArrayBufferFreeFunc;
+*/
+import "C"
+import (
+	fmt "fmt"
+	cgo "runtime/cgo"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+// a reference-counted block of shared memory, which backs one or more `SharedArrayBuffer`s (possibly in different runtimes).
+//
+// a [SharedBuffer] handle owns one reference to the block, which must be given up via [SharedBuffer.Release] once it is no longer needed.
+// the memory remains valid for as long as any runtime still has a `SharedArrayBuffer` over it.
+type SharedBuffer struct {
+	ptr  unsafe.Pointer
+	size int
+}
+
+// a registered shared memory block.
+type sharedBlock struct {
+	size int
+	refs int
+	// frees the memory once the last reference is released.
+	release func()
+}
+
+// the registry of all shared memory blocks of the process, keyed by their addresses.
+var sharedBlocks = struct {
+	mutex  sync.Mutex
+	blocks map[unsafe.Pointer]*sharedBlock
+}{blocks: map[unsafe.Pointer]*sharedBlock{}}
+
+// allocate a new zeroed block of c-memory with `size` bytes, and register it with a single reference.
+func allocSharedBlock(size int) unsafe.Pointer {
+	// a zero-sized block still needs a unique address, in order to be registered.
+	ptr := C.calloc(1, C.size_t(max(size, 1)))
+	if ptr == nil {
+		return nil
+	}
+	registerSharedBlock(ptr, size, func() { C.free(ptr) })
+	return ptr
+}
+
+// register a block of memory (that must never move) with a single reference.
+func registerSharedBlock(ptr unsafe.Pointer, size int, release func()) {
+	sharedBlocks.mutex.Lock()
+	defer sharedBlocks.mutex.Unlock()
+	sharedBlocks.blocks[ptr] = &sharedBlock{size: size, refs: 1, release: release}
+}
+
+// add a reference to a registered block, returning its size, or `false` if `ptr` is not a registered block.
+func dupSharedBlock(ptr unsafe.Pointer) (size int, ok bool) {
+	sharedBlocks.mutex.Lock()
+	defer sharedBlocks.mutex.Unlock()
+	block, ok := sharedBlocks.blocks[ptr]
+	if !ok {
+		return 0, false
+	}
+	block.refs++
+	return block.size, true
+}
+
+// drop a reference to a registered block, and release its memory once no references remain.
+func freeSharedBlock(ptr unsafe.Pointer) {
+	sharedBlocks.mutex.Lock()
+	block, ok := sharedBlocks.blocks[ptr]
+	if ok {
+		block.refs--
+		if block.refs > 0 {
+			ok = false
+		} else {
+			delete(sharedBlocks.blocks, ptr)
+		}
+	}
+	sharedBlocks.mutex.Unlock()
+	// the release happens outside of the lock, since unpinning go memory may take a while.
+	if ok {
+		block.release()
+	}
+}
+
+func (rt *Runtime) initSharedArrayBuffers() {
+	funcs := C.JSSharedArrayBufferFunctions{
+		sab_alloc: (*[0]byte)(unsafe.Pointer(&C.goSharedBufferAlloc)),
+		sab_free:  (*[0]byte)(unsafe.Pointer(&C.goSharedBufferFree)),
+		sab_dup:   (*[0]byte)(unsafe.Pointer(&C.goSharedBufferDup)),
+		// the runtime's handle, so that references to unknown blocks can be reported to it.
+		sab_opaque: C.handleToOpaque(C.uintptr_t(rt.handle)),
+	}
+	// quickjs copies the struct, so it need not outlive this call.
+	C.JS_SetSharedArrayBufferFunctions(rt.ref, &funcs)
+}
+
+//export goSharedBufferAlloc
+func goSharedBufferAlloc(opaque unsafe.Pointer, size C.size_t) unsafe.Pointer {
+	return allocSharedBlock(int(size))
+}
+
+//export goSharedBufferFree
+func goSharedBufferFree(opaque unsafe.Pointer, ptr unsafe.Pointer) {
+	freeSharedBlock(ptr)
+}
+
+// quickjs only duplicates the blocks of the `SharedArrayBuffer`s that it deserializes (see [Context.DeserializeWithOptions]),
+// which gives us no way of refusing an address that is not one of our blocks. instead, we record it on the runtime,
+// so that the deserialization can discard the bogus buffer before it is ever used.
+//
+//export goSharedBufferDup
+func goSharedBufferDup(opaque unsafe.Pointer, ptr unsafe.Pointer) {
+	if _, ok := dupSharedBlock(ptr); !ok {
+		rt := cgo.Handle(C.opaqueToHandle(opaque)).Value().(*Runtime)
+		rt.unknownSharedBlocks++
+	}
+}
+
+// the free function of the `SharedArrayBuffer`s that we create ourselves via `JS_NewArrayBuffer`.
+// quickjs prefers the runtime's `sab_free` over it, but both of them release the buffer's reference to its block.
+//
+//export sharedArrayBufferFreeFunc
+func sharedArrayBufferFreeFunc(rt *C.JSRuntime, opaque unsafe.Pointer, data_first_byte_ptr unsafe.Pointer) {
+	freeSharedBlock(data_first_byte_ptr)
+}
+
+// allocate a new zero-filled [SharedBuffer] of `size` bytes, which can then be shared with any number of runtimes via [Context.NewSharedArrayBuffer].
+// the returned handle must be released via [SharedBuffer.Release].
+func NewSharedBuffer(size int) *SharedBuffer {
+	ptr := allocSharedBlock(size)
+	if ptr == nil {
+		panic(fmt.Sprintf(`[NewSharedBuffer]: failed to allocate "%d" bytes.`, size))
+	}
+	return &SharedBuffer{ptr: ptr, size: size}
+}
+
+// get the shared memory as a byte slice, which remains valid until the handle is released.
+//
+// since the memory may be accessed by other threads at the same time, plain reads and writes are only safe
+// when they are otherwise synchronized (such as via [SharedBuffer.Wait] and [SharedBuffer.Notify]).
+// for everything else, use the functions of the `sync/atomic` package on properly aligned addresses.
+func (buf *SharedBuffer) Bytes() []byte {
+	if buf.size == 0 {
+		return []byte{}
+	}
+	return unsafe.Slice((*byte)(buf.ptr), buf.size)
+}
+
+// get the size of the shared memory in bytes.
+func (buf *SharedBuffer) Len() int {
+	return buf.size
+}
+
+// acquire another handle to the same shared memory, which must be released separately.
+func (buf *SharedBuffer) Dupe() *SharedBuffer {
+	dupSharedBlock(buf.ptr)
+	return &SharedBuffer{ptr: buf.ptr, size: buf.size}
+}
+
+// release the handle's reference to the shared memory. the handle must not be used afterwards.
+func (buf *SharedBuffer) Release() {
+	if buf.ptr != nil {
+		freeSharedBlock(buf.ptr)
+		buf.ptr = nil
+	}
+}
+
+// create a new javascript `SharedArrayBuffer` over the memory of `buf`, which adds a reference to it.
+// any number of runtimes (and goroutines) may share the same memory this way.
+//
+// @should-free
+func (ctx *Context) NewSharedArrayBuffer(buf *SharedBuffer) *Value {
+	dupSharedBlock(buf.ptr)
+	return ctx.newValue(C.JS_NewArrayBuffer(
+		ctx.ref, (*C.uint8_t)(buf.ptr), C.size_t(buf.size),
+		&C.sharedArrayBufferFreeFunc, nil, (C.JS_BOOL)(1),
+	))
+}
+
+// get a handle to the shared memory behind a javascript `SharedArrayBuffer` (or a typed array over one),
+// or `nil` if the value is not backed by shared memory. the returned handle must be released via [SharedBuffer.Release].
+func (val *Value) ToSharedBuffer() *SharedBuffer {
+	buffer := val
+	if val.IsTypedArray(TypedArrayAny) {
+		info := val.IdentifyTypedArrayInfo()
+		defer info.Buffer.Free()
+		buffer = info.Buffer
+	}
+	if !buffer.IsSharedArrayBuffer() {
+		return nil
+	}
+	var c_size C.size_t
+	ptr := unsafe.Pointer(C.JS_GetArrayBuffer(val.cctx(), &c_size, buffer.ref))
+	size, ok := dupSharedBlock(ptr)
+	if !ok {
+		return nil
+	}
+	return &SharedBuffer{ptr: ptr, size: size}
+}
@@ -0,0 +1,250 @@
+// this file contains the go-backed implementation of javascript's timer functions
+// (`setTimeout`, `setInterval`, `clearTimeout`, `clearInterval`, and `queueMicrotask`).
+//
+// the timers of all contexts of a runtime are kept in a single min-heap (ordered by their deadlines),
+// which is serviced by the runtime's event loop (see [Runtime.RunLoop]), using the runtime's [Clock] for measuring time.
+
+package bridge
+
+/*
+#include "./include0_quickjs.h"
+
+// forward declaration of the microtask job callback function, otherwise the compiler won't discover it.
+JSJobFunc goMicrotaskJob;
+*/
+import "C"
+import (
+	heap "container/heap"
+	errors "errors"
+	math "math"
+	time "time"
+)
+
+// a timer created by `setTimeout` or `setInterval`.
+type timer struct {
+	id       int32
+	deadline time.Time
+	// the order of creation (or rescheduling), which breaks the ties between timers that share the same deadline.
+	seq uint64
+	// the period of an interval timer, or `-1` for a one-shot timeout.
+	interval time.Duration
+	ctx      *Context
+	callback *Value
+	args     []*Value
+	// the index of the timer inside of the heap, or `-1` when it is not scheduled.
+	index int
+}
+
+func (t *timer) free() {
+	t.callback.Free()
+	for _, arg := range t.args {
+		arg.Free()
+	}
+}
+
+// a min-heap of timers, ordered by their deadlines (to implement the [heap.Interface]).
+type timerHeap []*timer
+
+func (h timerHeap) Len() int { return len(h) }
+func (h timerHeap) Less(i, j int) bool {
+	if h[i].deadline.Equal(h[j].deadline) {
+		return h[i].seq < h[j].seq
+	}
+	return h[i].deadline.Before(h[j].deadline)
+}
+func (h timerHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+func (h *timerHeap) Push(x any) {
+	t := x.(*timer)
+	t.index = len(*h)
+	*h = append(*h, t)
+}
+func (h *timerHeap) Pop() any {
+	old := *h
+	t := old[len(old)-1]
+	old[len(old)-1] = nil
+	t.index = -1
+	*h = old[:len(old)-1]
+	return t
+}
+
+// the timers of a runtime's event loop.
+type timerQueue struct {
+	clock  Clock
+	heap   timerHeap
+	byID   map[int32]*timer
+	lastID int32
+	seq    uint64
+}
+
+func newTimerQueue() *timerQueue {
+	return &timerQueue{clock: systemClock{}, byID: map[int32]*timer{}}
+}
+
+// set the [Clock] used by the timers of this runtime. a `nil` clock restores the default system clock.
+//
+// the clock should be set before any timer is created, since the deadlines of existing timers are not recomputed.
+func (rt *Runtime) SetClock(clock Clock) {
+	if clock == nil {
+		clock = systemClock{}
+	}
+	rt.loop.timers.clock = clock
+}
+
+func (queue *timerQueue) schedule(t *timer, delay time.Duration) {
+	queue.seq++
+	t.seq = queue.seq
+	t.deadline = queue.clock.Now().Add(delay)
+	heap.Push(&queue.heap, t)
+}
+
+func (queue *timerQueue) add(ctx *Context, callback *Value, args []*Value, delay time.Duration, repeat bool) int32 {
+	queue.lastID++
+	t := &timer{id: queue.lastID, interval: -1, ctx: ctx, callback: callback, args: args, index: -1}
+	if repeat {
+		t.interval = delay
+	}
+	queue.byID[t.id] = t
+	queue.schedule(t, delay)
+	return t.id
+}
+
+// clear the timer with the given `id`, but only if it was created by `ctx`.
+// the ids are numbered per runtime, so without the check, a context could cancel the timers of another context by guessing their ids.
+func (queue *timerQueue) clear(ctx *Context, id int32) {
+	t, ok := queue.byID[id]
+	if !ok || t.ctx != ctx {
+		return
+	}
+	delete(queue.byID, id)
+	if t.index >= 0 {
+		heap.Remove(&queue.heap, t.index)
+	}
+	t.free()
+}
+
+// get the timer with the earliest deadline, or `nil` if there are no timers.
+func (queue *timerQueue) next() *timer {
+	if len(queue.heap) == 0 {
+		return nil
+	}
+	return queue.heap[0]
+}
+
+// remove and return the earliest timer if its deadline has been reached, otherwise return `nil`.
+func (queue *timerQueue) popDue() *timer {
+	t := queue.next()
+	if t == nil || t.deadline.After(queue.clock.Now()) {
+		return nil
+	}
+	heap.Pop(&queue.heap)
+	return t
+}
+
+// execute the callback of a due timer (see [timerQueue.popDue]), rescheduling it if it is an interval.
+// an exception thrown by the callback is returned as an error.
+func (queue *timerQueue) fire(t *timer) error {
+	if t.interval >= 0 {
+		// the interval is rescheduled before its callback is called, so that the callback may clear it.
+		queue.schedule(t, t.interval)
+	} else {
+		delete(queue.byID, t.id)
+		defer t.free()
+	}
+	// the callback may clear its own timer (thereby freeing it), so we must hold on to it and its arguments until the call returns.
+	callback := t.callback.Dupe()
+	defer callback.Free()
+	args := make([]*Value, len(t.args))
+	for i, arg := range t.args {
+		args[i] = arg.Dupe()
+		defer args[i].Free()
+	}
+	result := callback.Call(nil, args...)
+	if result.IsException() {
+		return t.ctx.takeException()
+	}
+	result.Free()
+	return nil
+}
+
+// clear all timers belonging to a context that is about to be freed.
+func (queue *timerQueue) forgetContext(ctx *Context) {
+	for id, t := range queue.byID {
+		if t.ctx == ctx {
+			queue.clear(ctx, id)
+		}
+	}
+}
+
+//export goMicrotaskJob
+func goMicrotaskJob(ctx_ref *C.JSContext, argc C.int, argv *C.JSValue) C.JSValue {
+	// the job's only argument is the callback that was passed to `queueMicrotask`.
+	return C.JS_Call(ctx_ref, *argv, C.JS_UNDEFINED, 0, nil)
+}
+
+// register the timer functions (`setTimeout`, `setInterval`, `clearTimeout`, `clearInterval`, and `queueMicrotask`) on `globalThis`.
+//
+// the timers are executed by the runtime's event loop (see [Runtime.RunLoop]), and pending timers keep the loop alive.
+// an exception thrown by a timer's callback stops the event loop, and gets returned as its error.
+func (ctx *Context) RegisterTimers() {
+	global_this := ctx.GetGlobalThis()
+	global_this.Set("setTimeout", ctx.NewFunction("setTimeout", 1, func(ctx *Context, this *Value, args []*Value) (*Value, error) {
+		return ctx.newTimer(args, false)
+	}))
+	global_this.Set("setInterval", ctx.NewFunction("setInterval", 1, func(ctx *Context, this *Value, args []*Value) (*Value, error) {
+		return ctx.newTimer(args, true)
+	}))
+	clear_timer := func(ctx *Context, this *Value, args []*Value) (*Value, error) {
+		if args[0].IsNumber() {
+			ctx.rt.loop.timers.clear(ctx, args[0].ToInt32())
+		}
+		return nil, nil
+	}
+	// timeouts and intervals share the same pool of ids, hence why either function can clear either kind of timer (just like in browsers).
+	global_this.Set("clearTimeout", ctx.NewFunction("clearTimeout", 1, clear_timer))
+	global_this.Set("clearInterval", ctx.NewFunction("clearInterval", 1, clear_timer))
+	global_this.Set("queueMicrotask", ctx.NewFunction("queueMicrotask", 1, func(ctx *Context, this *Value, args []*Value) (*Value, error) {
+		if !args[0].IsFunction() {
+			return nil, errors.New("queueMicrotask: the callback is not a function")
+		}
+		if C.JS_EnqueueJob(ctx.ref, &C.goMicrotaskJob, 1, &args[0].ref) < 0 {
+			return nil, ctx.takeException()
+		}
+		return nil, nil
+	}))
+}
+
+// implements `setTimeout(callback, delay, ...args)` and `setInterval(callback, delay, ...args)`.
+//
+// @should-free
+func (ctx *Context) newTimer(args []*Value, repeat bool) (*Value, error) {
+	callback := args[0]
+	if !callback.IsFunction() {
+		return nil, errors.New("the timer's callback is not a function")
+	}
+	delay := time.Duration(0)
+	if len(args) > 1 {
+		// just like in browsers, invalid and negative delays become zero.
+		if ms := args[1].ToFloat64(); ms > 0 && ms < math.MaxInt64/float64(time.Millisecond) {
+			delay = time.Duration(ms * float64(time.Millisecond))
+		}
+	}
+	if repeat && delay < time.Millisecond {
+		// zero-delay intervals would otherwise starve the event loop, since they would always be due.
+		delay = time.Millisecond
+	}
+	// the arguments are borrowed, so we must dupe the ones that we hold on to.
+	var callback_args []*Value
+	if len(args) > 2 {
+		callback_args = make([]*Value, 0, len(args)-2)
+		for _, arg := range args[2:] {
+			callback_args = append(callback_args, arg.Dupe().Detach())
+		}
+	}
+	// the timer outlives any [Scope] that may be active right now, hence why its values are detached from it.
+	id := ctx.rt.loop.timers.add(ctx, callback.Dupe().Detach(), callback_args, delay, repeat)
+	return ctx.NewInt32(id), nil
+}
@@ -0,0 +1,98 @@
+// this file contains the error-returning (_try_) variants of the [Value] operations that may throw a javascript exception.
+//
+// unlike their counterparts (such as [Value.Call] and [Value.Set]), which either return a raw exception value or panic,
+// these methods take the pending exception out of the context (thereby clearing it), and return it as an [*Error].
+// this makes them the preferred choice for long-running hosts that execute untrusted or buggy scripts.
+
+package bridge
+
+/*
+#include "./include0_quickjs.h"
+*/
+import "C"
+import unsafe "unsafe"
+
+// take the pending exception out of the context (thereby clearing it), and return it as an [*Error].
+// if there is no pending exception, `nil` is returned.
+//
+// this is useful after a [Value.Call] (or any other operation) has returned an exception value (see [Value.IsException]).
+func (ctx *Context) GetException() error {
+	if C.JS_HasException(ctx.ref) == 0 {
+		return nil
+	}
+	return ctx.takeException()
+}
+
+// execute a javascript `Function`, just like [Value.Call], except that a thrown exception is returned as an [*Error].
+//
+// @should-free
+func (fun *Value) TryCall(this *Value, args ...*Value) (*Value, error) {
+	result := fun.Call(this, args...)
+	if result.IsException() {
+		return nil, fun.ctx.takeException()
+	}
+	return result, nil
+}
+
+// execute a class's constructor, just like [Value.CallConstructor], except that a thrown exception is returned as an [*Error].
+//
+// @should-free
+func (cls *Value) TryConstruct(args ...*Value) (*Value, error) {
+	result := cls.CallConstructor(args...)
+	if result.IsException() {
+		return nil, cls.ctx.takeException()
+	}
+	return result, nil
+}
+
+// get the value of a javascript `Object`'s property `prop`, just like [Value.Get],
+// except that an exception thrown by a getter (or by accessing the property of `undefined`) is returned as an [*Error].
+//
+// @should-free
+func (obj *Value) TryGet(prop string) (*Value, error) {
+	result := obj.Get(prop)
+	if result.IsException() {
+		return nil, obj.ctx.takeException()
+	}
+	return result, nil
+}
+
+// set a javascript `Object`'s property `prop` to a certain value `val`, just like [Value.Set],
+// except that a thrown exception (such as when the property is read-only) is returned as an [*Error] instead of panicking.
+//
+// the ownership of `val` is transferred even when an error is returned, so you must never free it yourself.
+//
+// @ownership-transfer
+func (obj *Value) TrySet(prop string, val *Value) error {
+	cstr_ptr := C.CString(prop)
+	defer C.free(unsafe.Pointer(cstr_ptr))
+	// success is either `-1` (exception), `0` (false), or `1` (true).
+	if C.JS_SetPropertyStr(obj.cctx(), obj.ref, cstr_ptr, val.transfer()) < 0 {
+		return obj.ctx.takeException()
+	}
+	return nil
+}
+
+// dictates whether or not an object has a certain property `prop`, just like [Value.Has],
+// except that a thrown exception (such as when `obj` is not an `Object`) is returned as an [*Error] instead of panicking.
+func (obj *Value) TryHas(prop string) (bool, error) {
+	prop_atom := obj.ctx.NewAtom(prop)
+	defer prop_atom.Free()
+	success := C.JS_HasProperty(obj.cctx(), obj.ref, prop_atom.ref)
+	if success < 0 {
+		return false, obj.ctx.takeException()
+	}
+	return success == 1, nil
+}
+
+// delete/remove a javascript `Object`'s property `prop`, just like [Value.Delete],
+// except that a thrown exception (such as when the property is not configurable) is returned as an [*Error] instead of panicking.
+func (obj *Value) TryDelete(prop string) (bool, error) {
+	prop_atom := obj.ctx.NewAtom(prop)
+	defer prop_atom.Free()
+	success := C.JS_DeleteProperty(obj.cctx(), obj.ref, prop_atom.ref, C.JS_PROP_THROW)
+	if success < 0 {
+		return false, obj.ctx.takeException()
+	}
+	return success == 1, nil
+}
@@ -43,6 +43,8 @@ type Value struct {
 	ctx *Context
 	// reference (or rather, composition) of the underlying c-based quickjs value.
 	ref C.JSValue
+	// the [Scope] that will free this value upon exiting, or `nil` if the value does not belong to any scope.
+	scope *Scope
 }
 
 // decrements the reference count of a javascript object.
@@ -57,7 +59,12 @@ func (val *Value) Free() {
 	if val == nil || val.ctx == nil {
 		return
 	}
-	C.JS_FreeValue(val.ctx.ref, val.ref)
+	val.ctx.rt.assertOwner()
+	if LeakDetection {
+		val.ctx.leaks.releaseValue(val, "freed")
+	}
+	val.scope = nil
+	C.JS_FreeValue(val.cctx(), val.ref)
 }
 
 // decrements the reference count of a javascript object _when_ its [Context] exits/frees up (i.e. when [Context.Free] is called).
@@ -65,8 +72,10 @@ func (val *Value) Free() {
 // this is opposed to freeing up the value _immediately_ via [Value.Free].
 // it is intended for long lived objects, such as polyfills (like `fetch`, `TextEncoder`, etc...),
 // that should be freed upon the context's destruction.
+//
+// under the hood, the value is moved over to the context's own [Scope], which exits when the context is freed.
 func (val *Value) FreeOnExit() {
-	val.ctx.valueFreeupList = append(val.ctx.valueFreeupList, val)
+	val.ctx.exitScope.addValue(val)
 }
 
 // increments the reference count of a javascript object and duplicates its [Value] wrapper.
@@ -78,12 +87,13 @@ func (val *Value) FreeOnExit() {
 // so be sure that your logic is sound if you're using this method.
 //
 // to decrement the reference count, use the [Value.Free] method.
+// like any other value, the duplicate gets recorded by the active [Scope] (if any), so use [Value.Detach] to keep it beyond the scope.
 func (val *Value) Dupe() *Value {
 	// the operation only takes place on non-nil values and contexts.
 	if val == nil || val.ctx == nil {
 		return nil
 	}
-	return &Value{ctx: val.ctx, ref: C.JS_DupValue(val.ctx.ref, val.ref)}
+	return val.ctx.newValue(C.JS_DupValue(val.cctx(), val.ref))
 }
 
 // get the [Context] of the value.
@@ -93,21 +103,21 @@ func (val *Value) GetContext() *Context {
 
 //------      TYPE CHECKS      ------//
 
-func (val *Value) IsNumber() bool        { return val != nil && C.JS_IsNumber(val.ref) == 1 }
-func (val *Value) IsBigInt() bool        { return val != nil && C.JS_IsBigInt(val.ctx.ref, val.ref) == 1 }
-func (val *Value) IsBool() bool          { return val != nil && C.JS_IsBool(val.ref) == 1 }
-func (val *Value) IsNull() bool          { return val != nil && C.JS_IsNull(val.ref) == 1 }
-func (val *Value) IsUndefined() bool     { return val != nil && C.JS_IsUndefined(val.ref) == 1 }
-func (val *Value) IsException() bool     { return val != nil && C.JS_IsException(val.ref) == 1 }
-func (val *Value) IsUninitialized() bool { return val != nil && C.JS_IsUninitialized(val.ref) == 1 }
-func (val *Value) IsString() bool        { return val != nil && C.JS_IsString(val.ref) == 1 }
-func (val *Value) IsSymbol() bool        { return val != nil && C.JS_IsSymbol(val.ref) == 1 }
-func (val *Value) IsObject() bool        { return val != nil && C.JS_IsObject(val.ref) == 1 }
-func (val *Value) IsError() bool         { return val != nil && C.JS_IsError(val.ctx.ref, val.ref) == 1 }
-func (val *Value) IsFunction() bool      { return val != nil && C.JS_IsFunction(val.ctx.ref, val.ref) == 1 }
+func (val *Value) IsNumber() bool        { return val.owned() && C.JS_IsNumber(val.ref) == 1 }
+func (val *Value) IsBigInt() bool        { return val != nil && C.JS_IsBigInt(val.cctx(), val.ref) == 1 }
+func (val *Value) IsBool() bool          { return val.owned() && C.JS_IsBool(val.ref) == 1 }
+func (val *Value) IsNull() bool          { return val.owned() && C.JS_IsNull(val.ref) == 1 }
+func (val *Value) IsUndefined() bool     { return val.owned() && C.JS_IsUndefined(val.ref) == 1 }
+func (val *Value) IsException() bool     { return val.owned() && C.JS_IsException(val.ref) == 1 }
+func (val *Value) IsUninitialized() bool { return val.owned() && C.JS_IsUninitialized(val.ref) == 1 }
+func (val *Value) IsString() bool        { return val.owned() && C.JS_IsString(val.ref) == 1 }
+func (val *Value) IsSymbol() bool        { return val.owned() && C.JS_IsSymbol(val.ref) == 1 }
+func (val *Value) IsObject() bool        { return val.owned() && C.JS_IsObject(val.ref) == 1 }
+func (val *Value) IsError() bool         { return val != nil && C.JS_IsError(val.cctx(), val.ref) == 1 }
+func (val *Value) IsFunction() bool      { return val != nil && C.JS_IsFunction(val.cctx(), val.ref) == 1 }
 func (val *Value) IsConstructor() bool {
 	// bloody gofmt won't let me place it in a single line.
-	return val != nil && C.JS_IsConstructor(val.ctx.ref, val.ref) == 1
+	return val != nil && C.JS_IsConstructor(val.cctx(), val.ref) == 1
 }
 
 //------     MISCELLANEOUS     ------//
@@ -136,7 +146,7 @@ func (ctx *Context) NewString(str string) *Value {
 	// note that `unsafe.Pointer` needs to be used here, despite `cstr_ptr` already being a pointer, because `C.free` accepts a generic `*void`,
 	// but our c-pointer is a `*char`, and go does not permit casting of `*char` to `*void` unless explicitly done via the `unsafe.Pointer` function.
 	defer C.free(unsafe.Pointer(cstr_ptr))
-	return &Value{ctx: ctx, ref: C.JS_NewStringLen(ctx.ref, cstr_ptr, cstr_len)}
+	return ctx.newValue(C.JS_NewStringLen(ctx.ref, cstr_ptr, cstr_len))
 }
 
 // returns the `string` representation of a value.
@@ -145,7 +155,7 @@ func (ctx *Context) NewString(str string) *Value {
 func (val *Value) ToString() string {
 	var cstr_len C.size_t
 	// the `JS_ToCStringLen` function returns a c-heap pointer to the string, in addition to also writing the byte-length of the string into the `cstr_len` variable.
-	cstr_ptr := C.JS_ToCStringLen(val.ctx.ref, &cstr_len, val.ref)
+	cstr_ptr := C.JS_ToCStringLen(val.cctx(), &cstr_len, val.ref)
 	// since the string that was created was by quickjs's allocator, it should be freed via its allocator as well, instead of `C.free` from the `<stdlib.h>`.
 	defer C.JS_FreeCString(val.ctx.ref, cstr_ptr)
 	// the reason for using `GoStringN` instead of `GoString` is that the returned string may contain null character, which we would want to include.
@@ -159,14 +169,14 @@ func (val *Value) ToString() string {
 //
 // note that it does not need to be freed afterwards.
 func (ctx *Context) NewNull() *Value {
-	return &Value{ctx: ctx, ref: C.JS_NULL}
+	return ctx.newValue(C.JS_NULL)
 }
 
 // create a new javascript `undefined` value.
 //
 // note that it does not need to be freed afterwards.
 func (ctx *Context) NewUndefined() *Value {
-	return &Value{ctx: ctx, ref: C.JS_UNDEFINED}
+	return ctx.newValue(C.JS_UNDEFINED)
 }
 
 // create a new "uninitialized" javascript value.
@@ -174,7 +184,7 @@ func (ctx *Context) NewUndefined() *Value {
 //
 // note that it does not need to be freed afterwards.
 func (ctx *Context) NewUninitialized() *Value {
-	return &Value{ctx: ctx, ref: C.JS_UNINITIALIZED}
+	return ctx.newValue(C.JS_UNINITIALIZED)
 }
 
 //------       BOOLEANS        ------//
@@ -184,9 +194,9 @@ func (ctx *Context) NewUninitialized() *Value {
 // note that it does not need to be freed afterwards.
 func (ctx *Context) NewBool(state bool) *Value {
 	if state {
-		return &Value{ctx: ctx, ref: C.JS_TRUE}
+		return ctx.newValue(C.JS_TRUE)
 	}
-	return &Value{ctx: ctx, ref: C.JS_FALSE}
+	return ctx.newValue(C.JS_FALSE)
 }
 
 // returns the truthiness of a javascript value.
@@ -195,7 +205,7 @@ func (ctx *Context) NewBool(state bool) *Value {
 func (val *Value) ToBool() bool {
 	// TODO: `JS_ToBool` may return `-1` when an exception is encountered.
 	// right now, I'm not handling that case, but what if I wanted to in the future? should I just call `panic()` and wipe my hands?
-	return C.JS_ToBool(val.ctx.ref, val.ref) == 1
+	return C.JS_ToBool(val.cctx(), val.ref) == 1
 }
 
 //------        NUMBERS        ------//
@@ -204,21 +214,21 @@ func (val *Value) ToBool() bool {
 //
 // note that it does not need to be freed afterwards.
 func (ctx *Context) NewInt32(value int32) *Value {
-	return &Value{ctx: ctx, ref: C.JS_NewInt32(ctx.ref, C.int32_t(value))}
+	return ctx.newValue(C.JS_NewInt32(ctx.ref, C.int32_t(value)))
 }
 
 // create a new javascript `number` value.
 //
 // note that it does not need to be freed afterwards.
 func (ctx *Context) NewUint32(value uint32) *Value {
-	return &Value{ctx: ctx, ref: C.JS_NewUint32(ctx.ref, C.uint32_t(value))}
+	return ctx.newValue(C.JS_NewUint32(ctx.ref, C.uint32_t(value)))
 }
 
 // create a new javascript `number` value.
 //
 // note that it does not need to be freed afterwards.
 func (ctx *Context) NewInt64(value int64) *Value {
-	return &Value{ctx: ctx, ref: C.JS_NewInt64(ctx.ref, C.int64_t(value))}
+	return ctx.newValue(C.JS_NewInt64(ctx.ref, C.int64_t(value)))
 }
 
 // create a new javascript `number` value.
@@ -234,14 +244,14 @@ func (ctx *Context) NewInt64(value int64) *Value {
 //
 // @should-free
 func (ctx *Context) NewBigInt64(value int64) *Value {
-	return &Value{ctx: ctx, ref: C.JS_NewBigInt64(ctx.ref, C.int64_t(value))}
+	return ctx.newValue(C.JS_NewBigInt64(ctx.ref, C.int64_t(value)))
 }
 
 // create a new javascript `bigint` value.
 //
 // @should-free
 func (ctx *Context) NewBigUint64(value uint64) *Value {
-	return &Value{ctx: ctx, ref: C.JS_NewBigUint64(ctx.ref, C.uint64_t(value))}
+	return ctx.newValue(C.JS_NewBigUint64(ctx.ref, C.uint64_t(value)))
 }
 
 // create a new javascript `bigint` value from go's [math_big.Int].
@@ -259,34 +269,34 @@ func (ctx *Context) NewBigInt(value *math_big.Int) *Value {
 //
 // note that it does not need to be freed afterwards.
 func (ctx *Context) NewFloat64(value float64) *Value {
-	return &Value{ctx: ctx, ref: C.JS_NewFloat64(ctx.ref, C.double(value))}
+	return ctx.newValue(C.JS_NewFloat64(ctx.ref, C.double(value)))
 }
 
 // returns the `int32` value of the value.
 func (val *Value) ToInt32() int32 {
 	cval := C.int32_t(0)
-	C.JS_ToInt32(val.ctx.ref, &cval, val.ref)
+	C.JS_ToInt32(val.cctx(), &cval, val.ref)
 	return int32(cval)
 }
 
 // returns the `uint32` value of the value.
 func (val *Value) ToUint32() uint32 {
 	cval := C.uint32_t(0)
-	C.JS_ToUint32(val.ctx.ref, &cval, val.ref)
+	C.JS_ToUint32(val.cctx(), &cval, val.ref)
 	return uint32(cval)
 }
 
 // returns the `int64` value of the value.
 func (val *Value) ToInt64() int64 {
 	cval := C.int64_t(0)
-	C.JS_ToInt64(val.ctx.ref, &cval, val.ref)
+	C.JS_ToInt64(val.cctx(), &cval, val.ref)
 	return int64(cval)
 }
 
 // returns the `int64` value of a `bigint`.
 func (val *Value) ToBigInt64() int64 {
 	cval := C.int64_t(0)
-	C.JS_ToBigInt64(val.ctx.ref, &cval, val.ref)
+	C.JS_ToBigInt64(val.cctx(), &cval, val.ref)
 	return int64(cval)
 }
 
@@ -302,7 +312,7 @@ func (val *Value) ToBigInt() *math_big.Int {
 // returns the `float64` value of the value.
 func (val *Value) ToFloat64() float64 {
 	cval := C.double(0)
-	C.JS_ToFloat64(val.ctx.ref, &cval, val.ref)
+	C.JS_ToFloat64(val.cctx(), &cval, val.ref)
 	return float64(cval)
 }
 
@@ -0,0 +1,451 @@
+// this file contains the go-backed implementation of the web worker api (the `Worker` class, along with `postMessage` and `onmessage`).
+//
+// each worker runs its script in a [Runtime] of its own, on a goroutine of its own (which is locked to its own os-thread),
+// so workers execute in parallel with their parent, and they share nothing with it but the messages that they exchange.
+//
+// messages are copied via the structured clone algorithm, which is implemented on top of [Value.Serialize] and [Context.Deserialize].
+// `ArrayBuffer`s listed in the `transfer` argument of `postMessage` are transferred:
+// they get detached in the sender (their `byteLength` becomes `0`), while the receiver gets their contents.
+// since the two sides have separate quickjs allocators, the contents are copied over rather than handed over.
+// `SharedArrayBuffer`s, on the other hand, are never copied, so both sides end up sharing the same memory (see `./shared.go`).
+//
+// the lifetime of a worker follows that of node.js: a worker exits once its event loop runs out of work while it has no `onmessage` handler,
+// or once it calls `close()`, or once its parent calls `worker.terminate()` (which interrupts whatever code the worker is running).
+// while a worker is alive, the event loop of its parent keeps waiting for it (see [Runtime.Reserve]).
+
+package bridge
+
+/*
+#include "./include0_quickjs.h"
+*/
+import "C"
+import (
+	context "context"
+	errors "errors"
+	fmt "fmt"
+	runtime "runtime"
+	sync_atomic "sync/atomic"
+	unsafe "unsafe"
+)
+
+// configures the workers spawned by the scripts of a context (see [Context.RegisterWorkers]).
+type WorkerOptions struct {
+	// resolves and loads the scripts of the workers (the specifier of `new Worker(specifier)` is resolved as if it were imported by the root module).
+	// it also becomes the module loader of every worker's runtime, so that the workers can `import` other modules.
+	Loader ModuleLoader
+	// an optional function that gets called on every worker's context before its script is evaluated, for installing additional globals.
+	// the timer functions (see [Context.RegisterTimers]) are always installed.
+	Init func(ctx *Context) error
+}
+
+// a worker spawned via `new Worker(...)`.
+//
+// the fields below are either immutable, or only ever accessed by the thread noted next to them.
+type worker struct {
+	options WorkerOptions
+	name    string // the normalized module name of the worker's script.
+	module  bool   // whether the script is an es-module (i.e. `new Worker(specifier, { type: "module" })`).
+	// the parent's context and its `Worker` object (parent thread).
+	parent   *Context
+	instance *Value
+	// set once `worker.terminate()` has been called, after which no more messages are delivered to the parent (parent thread).
+	terminated bool
+	// releases the reservation that keeps the parent's event loop alive, with the task that gets executed once the worker exits.
+	release func(task Task)
+	// the worker's own runtime and context (worker thread, with the exception of the goroutine-safe [Runtime.Post] and [Runtime.Interrupt]).
+	rt  *Runtime
+	ctx *Context
+	// the reservation that keeps the worker's event loop alive while it has an `onmessage` handler (worker thread).
+	keepAlive func(task Task)
+	// cancelled by `close()` and `worker.terminate()`, which stops the worker's event loop.
+	goctx  context.Context
+	cancel context.CancelFunc
+	// set once the worker's runtime has been freed, and closed right afterwards.
+	exited sync_atomic.Bool
+	done   chan struct{}
+}
+
+// the javascript source of the parent-side `Worker` class, which receives the go function that spawns the actual worker,
+// and returns the class. the go-side of the worker is kept in a private field, as an instance of the runtime's worker class.
+const workerClassSource = `((spawn) => class Worker {
+	#handle
+	onmessage = null
+	onerror = null
+	constructor(specifier, options = {}) {
+		this.#handle = spawn(this, String(specifier), options?.type === "module")
+	}
+	postMessage(message, transfer) { this.#handle.postMessage(message, transfer) }
+	terminate() { this.#handle.terminate() }
+})`
+
+// the javascript source that installs the worker-side globals (`self`, `postMessage`, `close`, and `onmessage`) on `globalThis`.
+// the `onmessage` property is an accessor, so that setting a handler keeps the worker's event loop alive (via the `keep_alive` go function).
+const workerScopeSource = `((post_message, close, keep_alive) => {
+	let handler = null
+	Object.defineProperties(globalThis, {
+		self: { value: globalThis, writable: true, configurable: true },
+		postMessage: { value: post_message, writable: true, configurable: true },
+		close: { value: close, writable: true, configurable: true },
+		onmessage: {
+			get: () => handler,
+			set: (fn) => {
+				handler = typeof fn === "function" ? fn : null
+				keep_alive(handler !== null)
+			},
+			configurable: true,
+		},
+	})
+})`
+
+// register the `Worker` class on `globalThis`, so that scripts can spawn workers via `new Worker("./worker.js")`.
+//
+// the messages sent by the workers are delivered by the parent's event loop (see [Runtime.RunLoop]).
+// an error that is thrown inside of a worker (and left uncaught) is dispatched to the `onerror` handler of its `Worker` object,
+// or, if there is no such handler, it stops the parent's event loop, and gets returned as its error.
+// all workers that are still alive when the context is freed get terminated.
+//
+// an error is returned if the `Worker` class could not be created (such as when the context runs out of memory while doing so),
+// in which case `globalThis.Worker` is left untouched.
+func (ctx *Context) RegisterWorkers(options WorkerOptions) error {
+	rt := ctx.rt
+	if rt.workerClass == nil {
+		rt.workerClass = rt.NewClass(ClassDefinition{
+			Name: "WorkerHandle",
+			Methods: map[string]GoFunction{
+				"postMessage": func(ctx *Context, this *Value, args []*Value) (*Value, error) {
+					return nil, this.Opaque().(*worker).postToWorker(ctx, args)
+				},
+				"terminate": func(ctx *Context, this *Value, args []*Value) (*Value, error) {
+					this.Opaque().(*worker).terminate()
+					return nil, nil
+				},
+			},
+		})
+	}
+	ctx.DefineClass(rt.workerClass).Free()
+	spawn := ctx.NewFunction("spawn", 3, func(ctx *Context, this *Value, args []*Value) (*Value, error) {
+		w, err := ctx.spawnWorker(options, args[0], args[1].ToString(), args[2].ToBool())
+		if err != nil {
+			return nil, err
+		}
+		return ctx.NewClassInstance(ctx.rt.workerClass, w), nil
+	})
+	defer spawn.Free()
+	factory, err := ctx.Eval(workerClassSource)
+	if err != nil {
+		return fmt.Errorf(`[Context.RegisterWorkers]: failed to create the "Worker" class: %w`, err)
+	}
+	defer factory.Free()
+	worker_class, err := factory.TryCall(nil, spawn)
+	if err != nil {
+		return fmt.Errorf(`[Context.RegisterWorkers]: failed to create the "Worker" class: %w`, err)
+	}
+	ctx.GetGlobalThis().Set("Worker", worker_class)
+	return nil
+}
+
+// spawn a worker that runs the script of the given `specifier`, whose `Worker` object is `instance`.
+// this returns once the worker's runtime has been initialized, while its script gets loaded and evaluated in the background.
+func (ctx *Context) spawnWorker(options WorkerOptions, instance *Value, specifier string, module bool) (*worker, error) {
+	if options.Loader == nil {
+		return nil, errors.New("[Worker]: no loader has been provided for the scripts of workers")
+	}
+	name, err := options.Loader.Normalize("", specifier)
+	if err != nil {
+		return nil, err
+	}
+	w := &worker{
+		options:  options,
+		name:     name,
+		module:   module,
+		parent:   ctx,
+		instance: instance.Dupe().Detach(),
+		done:     make(chan struct{}),
+	}
+	w.goctx, w.cancel = context.WithCancel(context.Background())
+	// the reservation must be in place before the worker starts, since a short-lived worker may exit right away.
+	w.release = ctx.rt.Reserve()
+	created := make(chan error)
+	go w.run(created)
+	if err := <-created; err != nil {
+		w.release(nil)
+		w.instance.Free()
+		return nil, err
+	}
+	if ctx.workers == nil {
+		ctx.workers = map[*worker]struct{}{}
+	}
+	ctx.workers[w] = struct{}{}
+	return w, nil
+}
+
+// the body of the worker's goroutine, which reports the outcome of initializing the worker's runtime through `created`.
+func (w *worker) run(created chan<- error) {
+	// the thread is intentionally never unlocked, so that it gets terminated along with the goroutine (see [NewLockedRuntime]).
+	runtime.LockOSThread()
+	defer close(w.done)
+	if err := w.init(); err != nil {
+		if w.rt != nil {
+			w.free()
+		}
+		created <- err
+		return
+	}
+	created <- nil
+	defer func() {
+		w.free()
+		// the parent is notified once the worker is gone, so that it can let go of the `Worker` object.
+		w.release(func() error {
+			w.parent.forgetWorker(w)
+			return nil
+		})
+	}()
+
+	rt := w.rt
+	defer rt.bindContext(w.goctx)()
+	err := w.evaluate()
+	for {
+		if err != nil {
+			if w.goctx.Err() != nil {
+				return
+			}
+			w.postError(err)
+		}
+		if err = rt.RunLoop(w.goctx); err == nil {
+			// the event loop has run out of work, and there is no `onmessage` handler to wait for.
+			return
+		}
+	}
+}
+
+// create and set up the worker's runtime and context.
+func (w *worker) init() error {
+	rt := NewRuntime()
+	if rt == nil {
+		return errors.New("[Worker]: failed to create a runtime")
+	}
+	w.rt = rt
+	ctx := rt.NewContext()
+	if ctx == nil {
+		return errors.New("[Worker]: failed to create a context")
+	}
+	w.ctx = ctx
+	rt.SetModuleLoader(w.options.Loader)
+	ctx.RegisterTimers()
+	post_message := ctx.NewFunction("postMessage", 1, func(ctx *Context, this *Value, args []*Value) (*Value, error) {
+		return nil, w.postToParent(ctx, args)
+	})
+	close_fn := ctx.NewFunction("close", 0, func(ctx *Context, this *Value, args []*Value) (*Value, error) {
+		w.cancel()
+		return nil, nil
+	})
+	keep_alive := ctx.NewFunction("keepAlive", 1, func(ctx *Context, this *Value, args []*Value) (*Value, error) {
+		if want := args[0].ToBool(); want && w.keepAlive == nil {
+			w.keepAlive = rt.Reserve()
+		} else if !want && w.keepAlive != nil {
+			w.keepAlive(nil)
+			w.keepAlive = nil
+		}
+		return nil, nil
+	})
+	defer post_message.Free()
+	defer close_fn.Free()
+	defer keep_alive.Free()
+	installer, err := ctx.Eval(workerScopeSource)
+	if err != nil {
+		return err
+	}
+	defer installer.Free()
+	result, err := installer.TryCall(nil, post_message, close_fn, keep_alive)
+	if err != nil {
+		return err
+	}
+	result.Free()
+	if w.options.Init != nil {
+		return w.options.Init(ctx)
+	}
+	return nil
+}
+
+// load and evaluate the worker's script.
+func (w *worker) evaluate() error {
+	source, err := w.options.Loader.Load(w.name)
+	if err != nil {
+		return err
+	}
+	var result *Value
+	if w.module {
+		result, err = w.ctx.EvalModule(w.name, source)
+	} else {
+		result, err = w.ctx.Eval(source)
+	}
+	result.Free()
+	return err
+}
+
+// free the worker's context and runtime.
+func (w *worker) free() {
+	if w.ctx != nil {
+		w.ctx.Free()
+	}
+	w.rt.Free()
+	w.exited.Store(true)
+}
+
+// terminate the worker, by interrupting whatever code it is running, and stopping its event loop.
+// this is called from the parent thread, and it does not wait for the worker to exit.
+func (w *worker) terminate() {
+	w.terminated = true
+	w.cancel()
+	w.rt.Interrupt()
+}
+
+// implements the parent-side `worker.postMessage(message, transfer)`, by cloning the message and delivering it on the worker's thread.
+func (w *worker) postToWorker(ctx *Context, args []*Value) error {
+	msg, err := serializeMessage(ctx, args)
+	if err != nil {
+		return err
+	}
+	if w.terminated || w.exited.Load() {
+		msg.release()
+		return nil
+	}
+	w.rt.Post(func() error {
+		defer msg.release()
+		if w.goctx.Err() != nil {
+			return nil
+		}
+		return dispatchMessage(w.ctx, w.ctx.GetGlobalThis(), msg)
+	})
+	return nil
+}
+
+// implements the worker-side `postMessage(message, transfer)`, by cloning the message and delivering it on the parent's thread.
+func (w *worker) postToParent(ctx *Context, args []*Value) error {
+	msg, err := serializeMessage(ctx, args)
+	if err != nil {
+		return err
+	}
+	w.parent.rt.Post(func() error {
+		defer msg.release()
+		if w.terminated || w.instance == nil {
+			return nil
+		}
+		return dispatchMessage(w.parent, w.instance, msg)
+	})
+	return nil
+}
+
+// deliver an uncaught error of the worker to the `onerror` handler of its `Worker` object, on the parent's thread.
+func (w *worker) postError(err error) {
+	message := err.Error()
+	w.parent.rt.Post(func() error {
+		if w.terminated || w.instance == nil {
+			return nil
+		}
+		ctx := w.parent
+		return ctx.WithScope(func(s *Scope) error {
+			handler := w.instance.Get("onerror")
+			if !handler.IsFunction() {
+				return fmt.Errorf("[Worker]: uncaught error in %q: %s", w.name, message)
+			}
+			event := ctx.NewObject()
+			event.Set("type", ctx.NewString("error"))
+			event.Set("message", ctx.NewString(message))
+			event.Set("filename", ctx.NewString(w.name))
+			_, err := handler.TryCall(w.instance, event)
+			return err
+		})
+	})
+}
+
+// let go of an exited (or terminated) worker's `Worker` object.
+func (ctx *Context) forgetWorker(w *worker) {
+	if _, ok := ctx.workers[w]; !ok {
+		return
+	}
+	delete(ctx.workers, w)
+	w.instance.Free()
+	w.instance = nil
+}
+
+// terminate all workers of a context that is about to be freed, and wait for them to exit.
+func (ctx *Context) terminateWorkers() {
+	for w := range ctx.workers {
+		w.terminate()
+		<-w.done
+		ctx.forgetWorker(w)
+	}
+}
+
+// a cloned message in transit between a worker and its parent.
+type workerMessage struct {
+	data []byte
+	// the shared memory blocks of the `SharedArrayBuffer`s inside of the message, which are kept alive until it is delivered (see [Value.serialize]).
+	shared []unsafe.Pointer
+}
+
+// release the shared memory blocks of the message, once it has been delivered (or dropped).
+func (msg workerMessage) release() {
+	for _, ptr := range msg.shared {
+		freeSharedBlock(ptr)
+	}
+}
+
+// clone the `message` of `postMessage(message, transfer)` (the `args`), and then detach the transferred `ArrayBuffer`s.
+// the `transfer` argument may either be an array, or an options object with a `transfer` array (i.e. `{ transfer: [buffer] }`).
+// `SharedArrayBuffer`s are not copied, but shared with the receiver instead (see `./shared.go`).
+func serializeMessage(ctx *Context, args []*Value) (msg workerMessage, err error) {
+	err = ctx.WithScope(func(s *Scope) error {
+		var transfer []*Value
+		if len(args) > 1 && args[1].IsObject() {
+			list := args[1]
+			if !list.IsArray() {
+				list = list.Get("transfer")
+			}
+			if list.IsArray() {
+				for i := range int64(list.Len()) {
+					buffer := list.GetIdx(i)
+					if !buffer.IsArrayBuffer() {
+						return errors.New("[postMessage]: only `ArrayBuffer`s can be transferred")
+					}
+					transfer = append(transfer, buffer)
+				}
+			}
+		}
+		msg.data, msg.shared, err = args[0].serialize(SerializeOptions{References: true}, true)
+		if err != nil {
+			return err
+		}
+		for _, buffer := range transfer {
+			C.JS_DetachArrayBuffer(ctx.ref, buffer.ref)
+		}
+		return nil
+	})
+	if err != nil {
+		msg.release()
+		return workerMessage{}, err
+	}
+	return msg, nil
+}
+
+// deserialize a cloned message, and pass it to the `onmessage` handler of `target` as the `data` of a message event.
+// an exception thrown by the handler is returned as an error.
+func dispatchMessage(ctx *Context, target *Value, msg workerMessage) error {
+	return ctx.WithScope(func(s *Scope) error {
+		message, err := ctx.DeserializeWithOptions(msg.data, DeserializeOptions{SharedArrayBuffers: true})
+		if err != nil {
+			return err
+		}
+		handler := target.Get("onmessage")
+		if !handler.IsFunction() {
+			return nil
+		}
+		event := ctx.NewObject()
+		event.Set("type", ctx.NewString("message"))
+		event.Set("data", message)
+		event.Set("target", target.Dupe())
+		_, err = handler.TryCall(target, event)
+		return err
+	})
+}
@@ -0,0 +1,201 @@
+// this file contains tests for `atomics.go` file under the [bridge] package.
+
+package bridge_test
+
+import (
+	context "context"
+	errors "errors"
+	sync_atomic "sync/atomic"
+	testing "testing"
+	time "time"
+
+	js "github.com/oazmi/quiccjs/pkg/bridge"
+)
+
+// notify the waiters of the `index`th integer of `buf` until one of them has been woken up (since the waiter may not be asleep yet).
+func notifyOne(buf *js.SharedBuffer, index int) {
+	for buf.Notify(index, 1) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestAtomics(t *testing.T) {
+	rt := js.NewRuntime()
+	defer rt.Free()
+	ctx := rt.NewContext()
+	defer ctx.Free()
+	buf := js.NewSharedBuffer(16)
+	defer buf.Release()
+	sab := ctx.NewSharedArrayBuffer(buf)
+	ctx.GetGlobalThis().Set("view", ctx.NewTypedArrayFromArrayBuffer(js.TypedArrayInt32, sab))
+	sab.Free()
+
+	// evaluate `code` and return its result as a string.
+	eval := func(t *testing.T, test_name string, code string) string {
+		result, err := ctx.Eval(code)
+		if err != nil {
+			t.Fatalf(`[eval check]: unexpected error: "%v", for test: "%s"`, err, test_name)
+		}
+		defer result.Free()
+		return result.ToString()
+	}
+
+	test_name := "Atomics.wait - not-equal and timed-out"
+	t.Run(test_name, func(t *testing.T) {
+		result := eval(t, test_name, `[Atomics.wait(view, 0, 1), Atomics.wait(view, 0, 0, 10)].join(",")`)
+		if result != "not-equal,timed-out" {
+			t.Errorf(`[result check]: expected "not-equal,timed-out", got: "%s", for test: "%s"`, result, test_name)
+		}
+	})
+
+	test_name = "Atomics.wait - woken up by a goroutine"
+	t.Run(test_name, func(t *testing.T) {
+		go func() {
+			sync_atomic.StoreInt32(buf.Int32(1), 42)
+			notifyOne(buf, 0)
+		}()
+		result := eval(t, test_name, `Atomics.wait(view, 0, 0) + ": " + Atomics.load(view, 1)`)
+		if result != "ok: 42" {
+			t.Errorf(`[result check]: expected "ok: 42", got: "%s", for test: "%s"`, result, test_name)
+		}
+	})
+
+	test_name = "SharedBuffer.Wait - woken up by javascript"
+	t.Run(test_name, func(t *testing.T) {
+		goctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		results := make(chan js.WaitResult, 1)
+		go func() {
+			result, _ := buf.Wait(goctx, 2, 0)
+			results <- result
+		}()
+		for eval(t, test_name, `Atomics.store(view, 3, 7); Atomics.notify(view, 2, 1)`) == "0" {
+			time.Sleep(time.Millisecond)
+		}
+		if result := <-results; result != js.WaitOk || sync_atomic.LoadInt32(buf.Int32(3)) != 7 {
+			t.Errorf(`[result check]: expected "ok", got: "%s", for test: "%s"`, result, test_name)
+		}
+		if result, _ := buf.Wait(goctx, 3, 0); result != js.WaitNotEqual {
+			t.Errorf(`[result check]: expected "not-equal", got: "%s", for test: "%s"`, result, test_name)
+		}
+		expired_ctx, cancel_expired := context.WithTimeout(goctx, 10*time.Millisecond)
+		defer cancel_expired()
+		if result, err := buf.Wait(expired_ctx, 2, 0); result != js.WaitTimedOut || !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf(`[result check]: expected "timed-out", got: "%s" (error: "%v"), for test: "%s"`, result, err, test_name)
+		}
+	})
+
+	test_name = "Atomics.notify - index and count conversions"
+	t.Run(test_name, func(t *testing.T) {
+		goctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		results := make(chan js.WaitResult, 1)
+		go func() {
+			result, _ := buf.Wait(goctx, 0, 0)
+			results <- result
+		}()
+		time.Sleep(20 * time.Millisecond)
+		// a `NaN` (or negative) count wakes up nobody, and a fractional (or negative zero) index is truncated.
+		result := eval(t, test_name, `[Atomics.notify(view, 0, NaN), Atomics.notify(view, -0.5, -1), Atomics.notify(view, "0", 0.9)].join(",")`)
+		if result != "0,0,0" {
+			t.Errorf(`[count check]: expected "0,0,0", got: "%s", for test: "%s"`, result, test_name)
+		}
+		// the retries are bounded, since a waiter that was wrongly woken up above would never be notified here.
+		for retries := 0; retries < 1000 && eval(t, test_name, `Atomics.notify(view, 0.9, 1.5)`) == "0"; retries++ {
+			time.Sleep(time.Millisecond)
+		}
+		if result := <-results; result != js.WaitOk {
+			t.Errorf(`[result check]: expected "ok", got: "%s", for test: "%s"`, result, test_name)
+		}
+		result = eval(t, test_name, `[-1, 4, Infinity].map((index) => { try { Atomics.notify(view, index); return "no error" } catch { return "thrown" } }).join(",")`)
+		if result != "thrown,thrown,thrown" {
+			t.Errorf(`[range check]: expected "thrown,thrown,thrown", got: "%s", for test: "%s"`, result, test_name)
+		}
+	})
+
+	test_name = "Atomics.wait - interrupted, even inside of a try block"
+	t.Run(test_name, func(t *testing.T) {
+		stop := interruptRepeatedly(rt)
+		_, err := ctx.Eval(`try { Atomics.wait(view, 0, 0) } catch { "caught" }`)
+		stop()
+		if !errors.Is(err, js.ErrInterrupted) {
+			t.Errorf(`[error check]: expected "ErrInterrupted", got: "%v", for test: "%s"`, err, test_name)
+		}
+		if result := eval(t, test_name, `"recovered"`); result != "recovered" {
+			t.Errorf(`[recovery check]: expected "recovered", got: "%s", for test: "%s"`, result, test_name)
+		}
+	})
+
+	test_name = "Atomics.wait - inside of a worker"
+	t.Run(test_name, func(t *testing.T) {
+		ctx.RegisterTimers()
+		err := ctx.RegisterWorkers(js.WorkerOptions{Loader: mapModuleLoader{
+			"waiter.js": `onmessage = (event) => {
+				const view = new Int32Array(event.data)
+				postMessage(Atomics.wait(view, 0, 0) + ": " + Atomics.load(view, 1))
+				close()
+			}`,
+			"sleeper.js": `onmessage = (event) => { Atomics.wait(new Int32Array(event.data), 2, 0) }`,
+		}})
+		if err != nil {
+			t.Fatalf(`[register check]: unexpected error: "%v", for test: "%s"`, err, test_name)
+		}
+		go func() {
+			sync_atomic.StoreInt32(buf.Int32(1), 84)
+			notifyOne(buf, 0)
+		}()
+		eval(t, test_name, `
+			const waiter = new Worker("./waiter.js")
+			waiter.onmessage = (event) => { globalThis.waiter_state = event.data }
+			waiter.postMessage(view.buffer)
+			const sleeper = new Worker("./sleeper.js")
+			sleeper.postMessage(view.buffer)
+			setTimeout(() => sleeper.terminate(), 50)
+		`)
+		goctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := rt.RunLoop(goctx); err != nil {
+			t.Fatalf(`[loop check]: unexpected error: "%v", for test: "%s"`, err, test_name)
+		}
+		if result := eval(t, test_name, `waiter_state`); result != "ok: 84" {
+			t.Errorf(`[result check]: expected "ok: 84", got: "%s", for test: "%s"`, result, test_name)
+		}
+	})
+
+	test_name = "Atomics - spec error classes"
+	t.Run(test_name, func(t *testing.T) {
+		result := eval(t, test_name, `
+			const error_of = (fn) => { try { fn(); return "no error" } catch (e) { return e.constructor.name } }
+			;[
+				error_of(() => Atomics.wait(new Float64Array(new SharedArrayBuffer(8)), 0, 0, 0)),
+				error_of(() => Atomics.wait(new Int32Array(4), 0, 0, 0)),
+				error_of(() => Atomics.notify(new Uint8Array(4), 0)),
+				error_of(() => Atomics.wait(view, 4, 0, 0)),
+				error_of(() => Atomics.notify(view, -1)),
+				error_of(() => Atomics.wait(view, Symbol(), 0, 0)),
+			].join(",")
+		`)
+		expected := "TypeError,TypeError,TypeError,RangeError,RangeError,TypeError"
+		if result != expected {
+			t.Errorf(`[error check]: expected "%s", got: "%s", for test: "%s"`, expected, result, test_name)
+		}
+	})
+
+	test_name = "Atomics.wait - BigInt64Array values go through ToBigInt64"
+	t.Run(test_name, func(t *testing.T) {
+		result := eval(t, test_name, `
+			const big_view = new BigInt64Array(new SharedArrayBuffer(16))
+			big_view[1] = 1n
+			;[
+				Atomics.wait(big_view, 0, 1n, 0),
+				Atomics.wait(big_view, 0, "0", 0),
+				Atomics.wait(big_view, 1, true, 0),
+				(() => { try { Atomics.wait(big_view, 0, 0, 0) } catch (e) { return e.constructor.name } })(),
+			].join(",")
+		`)
+		expected := "not-equal,timed-out,timed-out,TypeError"
+		if result != expected {
+			t.Errorf(`[result check]: expected "%s", got: "%s", for test: "%s"`, expected, result, test_name)
+		}
+	})
+}
@@ -0,0 +1,131 @@
+// this file contains tests for `bind.go` file under the [bridge] package.
+
+package bridge_test
+
+import (
+	errors "errors"
+	testing "testing"
+
+	js "github.com/oazmi/quiccjs/pkg/bridge"
+)
+
+type bindTestServer struct {
+	Name     string
+	ID       int
+	HTTPPort uint16
+	Label    string `js:"title"`
+	Secret   string `js:"-"`
+	Tags     []string
+	hidden   int
+}
+
+func (server *bindTestServer) Greet(greeting string) string { return greeting + ", " + server.Name }
+
+func (server *bindTestServer) Divide(a float64, b float64) (float64, error) {
+	if b == 0 {
+		return 0, errors.New("division by zero")
+	}
+	return a / b, nil
+}
+
+func (server *bindTestServer) MinMax(values ...int) (int, int) {
+	low, high := values[0], values[0]
+	for _, value := range values {
+		low, high = min(low, value), max(high, value)
+	}
+	return low, high
+}
+
+// receives a borrowed javascript value as is.
+func (server *bindTestServer) TypeOf(val *js.Value) string {
+	switch {
+	case val.IsFunction():
+		return "function"
+	case val.IsObject():
+		return "object"
+	}
+	return "other"
+}
+
+func TestBind_BindStruct(t *testing.T) {
+	rt := js.NewRuntime()
+	defer rt.Free()
+	ctx := rt.NewContext()
+	defer ctx.Free()
+	server := &bindTestServer{Name: "alpha", ID: 1, HTTPPort: 8080, Label: "main", Secret: "hunter2", hidden: 3}
+	if err := ctx.BindStruct("server", server); err != nil {
+		t.Fatalf(`[bind check]: unexpected error: "%v"`, err)
+	}
+
+	// evaluate `code` and return its result as a string.
+	eval := func(t *testing.T, test_name string, code string) string {
+		result, err := ctx.Eval(code)
+		if err != nil {
+			t.Fatalf(`[eval check]: unexpected error: "%v", for test: "%s"`, err, test_name)
+		}
+		defer result.Free()
+		return result.ToString()
+	}
+
+	test_name := "BindStruct - camelCase names and struct tags"
+	t.Run(test_name, func(t *testing.T) {
+		result := eval(t, test_name, `JSON.stringify(Object.keys(server))`)
+		if expected := `["name","id","httpPort","title","tags"]`; result != expected {
+			t.Errorf(`[keys check]: expected "%s", got: "%s", for test: "%s"`, expected, result, test_name)
+		}
+		result = eval(t, test_name, `["greet", "divide", "minMax", "typeOf", "secret", "hidden"].map((key) => typeof server[key]).join(",")`)
+		if expected := "function,function,function,function,undefined,undefined"; result != expected {
+			t.Errorf(`[members check]: expected "%s", got: "%s", for test: "%s"`, expected, result, test_name)
+		}
+	})
+
+	test_name = "BindStruct - field accessors in both directions"
+	t.Run(test_name, func(t *testing.T) {
+		result := eval(t, test_name, `[server.name, server.id, server.httpPort, server.title].join(",")`)
+		if result != "alpha,1,8080,main" {
+			t.Errorf(`[getter check]: expected "alpha,1,8080,main", got: "%s", for test: "%s"`, result, test_name)
+		}
+		eval(t, test_name, `server.name = "beta"; server.id = 7; server.tags = ["a", "b"]`)
+		if server.Name != "beta" || server.ID != 7 || len(server.Tags) != 2 || server.Tags[1] != "b" {
+			t.Errorf(`[setter check]: unexpected struct: "%+v", for test: "%s"`, server, test_name)
+		}
+		server.ID = 9
+		if result := eval(t, test_name, `server.id`); result != "9" {
+			t.Errorf(`[getter check]: expected "9", got: "%s", for test: "%s"`, result, test_name)
+		}
+		result = eval(t, test_name, `try { server.httpPort = -1; "no error" } catch (err) { err.message }`)
+		if result == "no error" || server.HTTPPort != 8080 {
+			t.Errorf(`[conversion check]: expected the setter to reject "-1", got: "%s", for test: "%s"`, result, test_name)
+		}
+	})
+
+	test_name = "BindStruct - methods with converted arguments and results"
+	t.Run(test_name, func(t *testing.T) {
+		result := eval(t, test_name, `server.greet("hello")`)
+		if result != "hello, beta" {
+			t.Errorf(`[method check]: expected "hello, beta", got: "%s", for test: "%s"`, result, test_name)
+		}
+		result = eval(t, test_name, `[server.divide(9, 2), JSON.stringify(server.minMax(4, -2, 9)), server.typeOf(() => 1), server.typeOf({})].join(",")`)
+		if expected := "4.5,[-2,9],function,object"; result != expected {
+			t.Errorf(`[method check]: expected "%s", got: "%s", for test: "%s"`, expected, result, test_name)
+		}
+		result = eval(t, test_name, `try { server.divide(1, 0) } catch (err) { err.message }`)
+		if result != "division by zero" {
+			t.Errorf(`[error check]: expected "division by zero", got: "%s", for test: "%s"`, result, test_name)
+		}
+		result = eval(t, test_name, `try { server.greet(42); "no error" } catch (err) { "thrown" }`)
+		if result != "thrown" {
+			t.Errorf(`[argument check]: expected a mismatched argument to throw, got: "%s", for test: "%s"`, result, test_name)
+		}
+	})
+
+	test_name = "BindStruct - rejects non-struct-pointers"
+	t.Run(test_name, func(t *testing.T) {
+		var nil_server *bindTestServer
+		for _, v := range []any{bindTestServer{}, nil_server, new(int), nil} {
+			if err := ctx.BindStruct("invalid", v); err == nil {
+				t.Errorf(`[error check]: expected an error for "%T", for test: "%s"`, v, test_name)
+			}
+		}
+	})
+}
@@ -0,0 +1,91 @@
+// this file contains tests for `bytecode.go` file under the [bridge] package.
+
+package bridge_test
+
+import (
+	bytes "bytes"
+	errors "errors"
+	os "os"
+	strings "strings"
+	testing "testing"
+
+	js "github.com/oazmi/quiccjs/pkg/bridge"
+)
+
+func TestBytecode_CompileAndEval(t *testing.T) {
+	compiler_rt := js.NewRuntime()
+	defer compiler_rt.Free()
+	compiler_ctx := compiler_rt.NewContext()
+	defer compiler_ctx.Free()
+	rt := js.NewRuntime()
+	defer rt.Free()
+	ctx := rt.NewContext()
+	defer ctx.Free()
+
+	test_name := "EvalBytecode - script in a different runtime"
+	t.Run(test_name, func(t *testing.T) {
+		bytecode, err := compiler_ctx.Compile("script.js", `const square = (x) => x * x; square(7)`, false)
+		if err != nil {
+			t.Fatalf(`[compile check]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		result, err := ctx.EvalBytecode(bytecode)
+		if err != nil {
+			t.Fatalf(`[eval check ]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		defer result.Free()
+		if got := result.ToInt64(); got != 49 {
+			t.Errorf(`[value check]: expected value: "%d", got: "%d", for test: "%s"`, 49, got, test_name)
+		}
+	})
+
+	test_name = "EvalBytecode - module namespace"
+	t.Run(test_name, func(t *testing.T) {
+		bytecode, err := compiler_ctx.Compile("/module.js", `export const greeting = "hello " + "bytecode";`, true)
+		if err != nil {
+			t.Fatalf(`[compile check]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		namespace, err := ctx.EvalBytecode(bytecode)
+		if err != nil {
+			t.Fatalf(`[eval check ]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		defer namespace.Free()
+		greeting := namespace.Get("greeting")
+		defer greeting.Free()
+		if got := greeting.ToString(); got != "hello bytecode" {
+			t.Errorf(`[value check]: expected value: "%s", got: "%s", for test: "%s"`, "hello bytecode", got, test_name)
+		}
+	})
+
+	test_name = "Compile - syntax error"
+	t.Run(test_name, func(t *testing.T) {
+		if _, err := compiler_ctx.Compile("broken.js", `let = ;`, false); err == nil {
+			t.Errorf(`[error check]: expected a syntax error, for test: "%s"`, test_name)
+		}
+	})
+
+	test_name = "EvalBytecode - rejects a different version"
+	t.Run(test_name, func(t *testing.T) {
+		bytecode, err := compiler_ctx.Compile("script.js", `1 + 1`, false)
+		if err != nil {
+			t.Fatalf(`[compile check]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		tampered := bytes.Replace(bytecode, []byte(js.QuickjsVersion), []byte("2000-01-01"), 1)
+		if _, err := ctx.EvalBytecode(tampered); !errors.Is(err, js.ErrBytecodeVersion) {
+			t.Errorf(`[error check]: expected an "ErrBytecodeVersion" error, got: "%v", for test: "%s"`, err, test_name)
+		}
+		if _, err := ctx.EvalBytecode([]byte("not bytecode")); !errors.Is(err, js.ErrBytecodeVersion) {
+			t.Errorf(`[error check]: expected an "ErrBytecodeVersion" error, got: "%v", for test: "%s"`, err, test_name)
+		}
+	})
+}
+
+func TestBytecode_QuickjsVersion(t *testing.T) {
+	// the version of the vendored quickjs sources, which our bytecode header must carry.
+	vendored, err := os.ReadFile("../../vendor/quickjs/VERSION")
+	if err != nil {
+		t.Fatalf(`[file check]: failed to read the version of the vendored quickjs sources: "%v"`, err)
+	}
+	if got, expected := js.QuickjsVersion, strings.TrimSpace(string(vendored)); got != expected {
+		t.Errorf(`[version check]: expected "QuickjsVersion" to match the vendored quickjs version: "%s", got: "%s"`, expected, got)
+	}
+}
@@ -0,0 +1,143 @@
+// this file contains tests for `class.go` file under the [bridge] package.
+
+package bridge_test
+
+import (
+	runtime "runtime"
+	slices "slices"
+	testing "testing"
+	weak "weak"
+
+	js "github.com/oazmi/quiccjs/pkg/bridge"
+)
+
+type counter struct {
+	count int64
+	// pads the struct out of go's tiny allocator, so that weak pointers to it are released individually.
+	_ [64]byte
+}
+
+func TestClass(t *testing.T) {
+	rt := js.NewRuntime()
+	defer rt.Free()
+	finalized := []int64{}
+	counter_class := rt.NewClass(js.ClassDefinition{
+		Name:   "Counter",
+		Length: 1,
+		Constructor: func(ctx *js.Context, args []*js.Value) (any, error) {
+			return &counter{count: args[0].ToInt64()}, nil
+		},
+		Methods: map[string]js.GoFunction{
+			"increment": func(ctx *js.Context, this *js.Value, args []*js.Value) (*js.Value, error) {
+				data := this.Opaque().(*counter)
+				data.count++
+				return ctx.NewInt64(data.count), nil
+			},
+		},
+		Finalizer: func(data any) { finalized = append(finalized, data.(*counter).count) },
+	})
+	other_class := rt.NewClass(js.ClassDefinition{Name: "Other"})
+	ctx := rt.NewContext()
+	defer ctx.Free()
+	ctx.GetGlobalThis().Set("Counter", ctx.DefineClass(counter_class))
+	other_ctor := ctx.DefineClass(other_class)
+	other_ctor.Free()
+
+	// evaluate `code` and return its result as a string.
+	eval := func(t *testing.T, test_name string, code string) string {
+		result, err := ctx.Eval(code)
+		if err != nil {
+			t.Fatalf(`[eval check]: unexpected error: "%v", for test: "%s"`, err, test_name)
+		}
+		defer result.Free()
+		return result.ToString()
+	}
+
+	test_name := "DefineClass - constructed from javascript"
+	t.Run(test_name, func(t *testing.T) {
+		result := eval(t, test_name, `
+			const counter = new Counter(40)
+			counter.increment();
+			[counter.increment(), counter instanceof Counter, Object.keys(Counter.prototype).length].join(",")
+		`)
+		if result != "42,true,0" {
+			t.Errorf(`[result check]: expected "42,true,0", got: "%s", for test: "%s"`, result, test_name)
+		}
+		result = eval(t, test_name, `try { Counter(1) } catch (err) { err.message }`)
+		if result != `class constructor "Counter" cannot be invoked without "new"` {
+			t.Errorf(`[new check]: unexpected result: "%s", for test: "%s"`, result, test_name)
+		}
+		// neither a `this` object that looks like an instance, nor a `this` that is itself a constructor, may get past the check.
+		result = eval(t, test_name, `[Object.create(Counter.prototype), Counter].map((that) => {
+			try { Counter.call(that, 1); return "constructed" } catch (err) { return err.message }
+		}).join(",")`)
+		if expected := `class constructor "Counter" cannot be invoked without "new",class constructor "Counter" cannot be invoked without "new"`; result != expected {
+			t.Errorf(`[new check]: unexpected result for calls with a "this" object: "%s", for test: "%s"`, result, test_name)
+		}
+		result = eval(t, test_name, `class Sub extends Counter { double() { return this.increment() * 2 } }; new Sub(1).double()`)
+		if result != "4" {
+			t.Errorf(`[subclass check]: expected "4", got: "%s", for test: "%s"`, result, test_name)
+		}
+	})
+
+	test_name = "NewClassInstance - Opaque round trip and instance checks"
+	t.Run(test_name, func(t *testing.T) {
+		data := &counter{count: 7}
+		instance := ctx.NewClassInstance(counter_class, data)
+		defer instance.Free()
+		if opaque, ok := instance.Opaque().(*counter); !ok || opaque != data {
+			t.Errorf(`[opaque check]: expected the original go-data, got: "%v", for test: "%s"`, instance.Opaque(), test_name)
+		}
+		if !instance.IsInstanceOfClass(counter_class) || instance.IsInstanceOfClass(other_class) {
+			t.Errorf(`[instance check]: expected an instance of "Counter" only, for test: "%s"`, test_name)
+		}
+		plain := ctx.NewObject()
+		defer plain.Free()
+		if plain.Opaque() != nil || plain.IsInstanceOfClass(counter_class) {
+			t.Errorf(`[plain check]: expected a plain object to carry no go-data, for test: "%s"`, test_name)
+		}
+		ctx.GetGlobalThis().Set("instance", instance.Dupe())
+		if result := eval(t, test_name, `instance.increment()`); result != "8" || data.count != 8 {
+			t.Errorf(`[method check]: expected "8", got: "%s", for test: "%s"`, result, test_name)
+		}
+	})
+
+	test_name = "Finalizer - releases the go-data once collected"
+	t.Run(test_name, func(t *testing.T) {
+		spawn := func() weak.Pointer[counter] {
+			data := &counter{count: 99}
+			instance := ctx.NewClassInstance(counter_class, data)
+			instance.Free()
+			return weak.Make(data)
+		}
+		finalized = finalized[:0]
+		data := spawn()
+		eval(t, test_name, `new Counter(100); undefined`)
+		rt.RunGC()
+		runtime.GC()
+		if !slices.Contains(finalized, 99) || !slices.Contains(finalized, 100) {
+			t.Errorf(`[finalizer check]: expected "[99 100]", got: "%v", for test: "%s"`, finalized, test_name)
+		}
+		if data.Value() != nil {
+			t.Errorf(`[release check]: expected the go-data to be released, for test: "%s"`, test_name)
+		}
+	})
+
+	test_name = "Finalizer - runs when the context is freed"
+	t.Run(test_name, func(t *testing.T) {
+		finalized = finalized[:0]
+		other_ctx := rt.NewContext()
+		ctor := other_ctx.DefineClass(counter_class)
+		other_ctx.GetGlobalThis().Set("Counter", ctor)
+		kept, err := other_ctx.Eval(`globalThis.kept = new Counter(5); undefined`)
+		if err != nil {
+			t.Fatalf(`[eval check]: unexpected error: "%v", for test: "%s"`, err, test_name)
+		}
+		kept.Free()
+		other_ctx.Free()
+		rt.RunGC()
+		if !slices.Contains(finalized, 5) {
+			t.Errorf(`[finalizer check]: expected "[5]", got: "%v", for test: "%s"`, finalized, test_name)
+		}
+	})
+}
@@ -0,0 +1,173 @@
+// this file contains tests for `error.go` file under the [bridge] package.
+
+package bridge_test
+
+import (
+	errors "errors"
+	fmt "fmt"
+	strings "strings"
+	testing "testing"
+
+	js "github.com/oazmi/quiccjs/pkg/bridge"
+)
+
+func TestError_Eval(t *testing.T) {
+	rt := js.NewRuntime()
+	defer rt.Free()
+	ctx := rt.NewContext()
+	defer ctx.Free()
+
+	test_name := "Eval - thrown Error"
+	t.Run(test_name, func(t *testing.T) {
+		_, err := ctx.Eval(`function fail() { throw new TypeError("bad type", { cause: "testing" }) }; fail()`)
+		var js_err *js.Error
+		if !errors.As(err, &js_err) {
+			t.Fatalf(`[error check]: expected a "*js.Error", got: "%#v", for test: "%s"`, err, test_name)
+		}
+		if js_err.Name != "TypeError" || js_err.Message != "bad type" || js_err.Cause != "testing" {
+			t.Errorf(`[error check]: unexpected fields: "%#v", for test: "%s"`, js_err, test_name)
+		}
+		if !strings.Contains(js_err.Stack, "fail") {
+			t.Errorf(`[stack check]: expected the stack to mention "fail", got: "%s", for test: "%s"`, js_err.Stack, test_name)
+		}
+		if js_err.Thrown != nil {
+			t.Errorf(`[thrown check]: expected no thrown value for an "Error" instance, for test: "%s"`, test_name)
+		}
+	})
+
+	test_name = "Eval - thrown non-Error value is not captured by default"
+	t.Run(test_name, func(t *testing.T) {
+		_, err := ctx.Eval(`throw { code: 7 }`)
+		var js_err *js.Error
+		if !errors.As(err, &js_err) {
+			t.Fatalf(`[error check]: expected a "*js.Error", got: "%#v", for test: "%s"`, err, test_name)
+		}
+		if js_err.Thrown != nil {
+			t.Errorf(`[thrown check]: expected no thrown value without "SetCaptureThrown", for test: "%s"`, test_name)
+		}
+		if js_err.Message != "[object Object]" {
+			t.Errorf(`[error check]: unexpected message: "%s", for test: "%s"`, js_err.Message, test_name)
+		}
+	})
+
+	test_name = "Eval - thrown non-Error value"
+	t.Run(test_name, func(t *testing.T) {
+		ctx.SetCaptureThrown(true)
+		defer ctx.SetCaptureThrown(false)
+		_, err := ctx.Eval(`throw 42`)
+		var js_err *js.Error
+		if !errors.As(err, &js_err) {
+			t.Fatalf(`[error check]: expected a "*js.Error", got: "%#v", for test: "%s"`, err, test_name)
+		}
+		defer js_err.Free()
+		if js_err.Thrown == nil || js_err.Thrown.ToInt32() != 42 {
+			t.Fatalf(`[thrown check]: expected the thrown value "42", for test: "%s"`, test_name)
+		}
+		if js_err.Message != "42" || js_err.Name != "" {
+			t.Errorf(`[error check]: unexpected fields: "%#v", for test: "%s"`, js_err, test_name)
+		}
+	})
+
+	test_name = "Eval - thrown function is kept as is"
+	t.Run(test_name, func(t *testing.T) {
+		ctx.SetCaptureThrown(true)
+		defer ctx.SetCaptureThrown(false)
+		_, err := ctx.Eval(`throw function thrower() { return "thrown" }`)
+		var js_err *js.Error
+		if !errors.As(err, &js_err) {
+			t.Fatalf(`[error check]: expected a "*js.Error", got: "%#v", for test: "%s"`, err, test_name)
+		}
+		defer js_err.Free()
+		if js_err.Thrown == nil || !js_err.Thrown.IsFunction() {
+			t.Fatalf(`[thrown check]: expected the thrown function, for test: "%s"`, test_name)
+		}
+		result := js_err.Thrown.Call(nil)
+		defer result.Free()
+		if got := result.ToString(); got != "thrown" {
+			t.Errorf(`[value check]: expected value: "%s", got: "%s", for test: "%s"`, "thrown", got, test_name)
+		}
+		js_err.Free()
+		if js_err.Thrown != nil {
+			t.Errorf(`[thrown check]: expected "Free" to clear the thrown value, for test: "%s"`, test_name)
+		}
+	})
+
+	test_name = "Eval - syntax error"
+	t.Run(test_name, func(t *testing.T) {
+		_, err := ctx.Eval(`let = = 1`)
+		var js_err *js.Error
+		if !errors.As(err, &js_err) || js_err.Name != "SyntaxError" {
+			t.Errorf(`[error check]: expected a "SyntaxError", got: "%v", for test: "%s"`, err, test_name)
+		}
+	})
+}
+
+func TestError_GoRoundTrip(t *testing.T) {
+	rt := js.NewRuntime()
+	defer rt.Free()
+	ctx := rt.NewContext()
+	defer ctx.Free()
+
+	sentinel := errors.New("sentinel")
+	global_this := ctx.GetGlobalThis()
+	global_this.Set("fail", ctx.NewFunction("fail", 0, func(ctx *js.Context, this *js.Value, args []*js.Value) (*js.Value, error) {
+		return nil, fmt.Errorf("wrapped: %w", sentinel)
+	}))
+	global_this.Set("explode", ctx.NewFunction("explode", 0, func(ctx *js.Context, this *js.Value, args []*js.Value) (*js.Value, error) {
+		panic("boom")
+	}))
+
+	test_name := "round trip - rethrown go error"
+	t.Run(test_name, func(t *testing.T) {
+		_, err := ctx.Eval(`try { fail() } catch (e) { throw e }`)
+		if !errors.Is(err, sentinel) {
+			t.Errorf(`[error check]: expected the error to wrap the sentinel, got: "%v", for test: "%s"`, err, test_name)
+		}
+	})
+
+	test_name = "round trip - through Value.Call"
+	t.Run(test_name, func(t *testing.T) {
+		fn, err := ctx.Eval(`() => fail()`)
+		if err != nil {
+			t.Fatalf(`[eval check]: unexpected error: "%v", for test: "%s"`, err, test_name)
+		}
+		defer fn.Free()
+		_, err = fn.TryCall(nil)
+		var js_err *js.Error
+		if !errors.As(err, &js_err) || js_err.Message != "wrapped: sentinel" || !errors.Is(err, sentinel) {
+			t.Errorf(`[error check]: expected the error to wrap the sentinel, got: "%v", for test: "%s"`, err, test_name)
+		}
+	})
+
+	test_name = "round trip - the holder property resists tampering"
+	t.Run(test_name, func(t *testing.T) {
+		_, err := ctx.Eval(`try { fail() } catch (e) {
+			for (const symbol of Object.getOwnPropertySymbols(e)) { e[symbol] = null; delete e[symbol] }
+			throw e
+		}`)
+		if !errors.Is(err, sentinel) {
+			t.Errorf(`[error check]: expected the error to wrap the sentinel, got: "%v", for test: "%s"`, err, test_name)
+		}
+	})
+
+	test_name = "round trip - plain javascript error"
+	t.Run(test_name, func(t *testing.T) {
+		_, err := ctx.Eval(`throw new Error("not from go")`)
+		if errors.Unwrap(err) != nil {
+			t.Errorf(`[error check]: expected nothing to unwrap, got: "%v", for test: "%s"`, errors.Unwrap(err), test_name)
+		}
+	})
+
+	test_name = "round trip - panic"
+	t.Run(test_name, func(t *testing.T) {
+		_, err := ctx.Eval(`explode()`)
+		var panic_err *js.PanicError
+		if !errors.As(err, &panic_err) || panic_err.Value != "boom" {
+			t.Fatalf(`[error check]: expected a "*js.PanicError", got: "%v", for test: "%s"`, err, test_name)
+		}
+		var js_err *js.Error
+		if !errors.As(err, &js_err) || !strings.Contains(js_err.Stack, "goroutine") {
+			t.Errorf(`[stack check]: expected the go stack trace, got: "%v", for test: "%s"`, js_err, test_name)
+		}
+	})
+}
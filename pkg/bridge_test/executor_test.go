@@ -0,0 +1,194 @@
+// this file contains tests for `executor.go` file under the [bridge] package.
+
+package bridge_test
+
+import (
+	errors "errors"
+	sync "sync"
+	testing "testing"
+
+	js "github.com/oazmi/quiccjs/pkg/bridge"
+)
+
+func TestExecutor(t *testing.T) {
+	rt := js.NewLockedRuntime()
+	if rt == nil {
+		t.Fatal(`[runtime check]: failed to create a locked runtime`)
+	}
+
+	test_name := "Do - concurrent goroutines"
+	t.Run(test_name, func(t *testing.T) {
+		err := rt.Do(func(ctx *js.Context) error {
+			val, err := ctx.Eval(`globalThis.counter = 0`)
+			val.Free()
+			return err
+		})
+		if err != nil {
+			t.Fatalf(`[eval check]: unexpected error: "%v", for test: "%s"`, err, test_name)
+		}
+		var wg sync.WaitGroup
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for range 100 {
+					rt.Do(func(ctx *js.Context) error {
+						val, err := ctx.Eval(`counter++`)
+						val.Free()
+						return err
+					})
+				}
+			}()
+		}
+		wg.Wait()
+		var counter int64
+		rt.Do(func(ctx *js.Context) error {
+			val, err := ctx.Eval(`counter`)
+			if err != nil {
+				return err
+			}
+			defer val.Free()
+			counter = val.ToInt64()
+			return nil
+		})
+		if counter != 800 {
+			t.Errorf(`[value check]: expected "800", got: "%d", for test: "%s"`, counter, test_name)
+		}
+	})
+
+	test_name = "Do - nested call and returned error"
+	t.Run(test_name, func(t *testing.T) {
+		err := rt.Do(func(ctx *js.Context) error {
+			return rt.Do(func(inner_ctx *js.Context) error {
+				if inner_ctx != ctx {
+					t.Errorf(`[context check]: expected the nested call to receive the same context, for test: "%s"`, test_name)
+				}
+				_, err := ctx.Eval(`throw new Error("nested")`)
+				return err
+			})
+		})
+		var js_err *js.Error
+		if !errors.As(err, &js_err) || js_err.Message != "nested" {
+			t.Errorf(`[error check]: expected the "nested" error, got: "%v", for test: "%s"`, err, test_name)
+		}
+	})
+
+	test_name = "Do - panic is re-raised in the caller"
+	t.Run(test_name, func(t *testing.T) {
+		defer func() {
+			panic_err, ok := recover().(*js.PanicError)
+			if !ok || panic_err.Value != "boom" {
+				t.Errorf(`[panic check]: expected a "*PanicError" of "boom", for test: "%s"`, test_name)
+			}
+		}()
+		rt.Do(func(ctx *js.Context) error { panic("boom") })
+	})
+
+	test_name = "Do - cross-goroutine misuse"
+	t.Run(test_name, func(t *testing.T) {
+		if !js.LeakDetection {
+			t.Skip(`the ownership assertion is only compiled in with the "quiccjs_debug" build tag`)
+		}
+		var obj *js.Value
+		rt.Do(func(ctx *js.Context) error {
+			obj = ctx.NewObject()
+			return nil
+		})
+		misuses := map[string]func(){
+			"Free":     func() { obj.Free() },
+			"ToString": func() { obj.ToString() },
+			"IsObject": func() { obj.IsObject() },
+			"Get":      func() { obj.Get("key") },
+		}
+		for name, misuse := range misuses {
+			func() {
+				defer func() {
+					if recover() == nil {
+						t.Errorf(`[panic check]: expected "%s" outside of the executor to panic, for test: "%s"`, name, test_name)
+					}
+				}()
+				misuse()
+			}()
+		}
+		rt.Do(func(ctx *js.Context) error {
+			obj.Free()
+			return nil
+		})
+	})
+
+	test_name = "Free - stops the executor"
+	t.Run(test_name, func(t *testing.T) {
+		rt.Free()
+		err := rt.Do(func(ctx *js.Context) error { return nil })
+		if !errors.Is(err, js.ErrRuntimeFreed) {
+			t.Errorf(`[error check]: expected "ErrRuntimeFreed", got: "%v", for test: "%s"`, err, test_name)
+		}
+	})
+}
+
+func TestExecutor_PlainRuntimeOwnership(t *testing.T) {
+	rt := js.NewRuntime()
+	defer rt.Free()
+	ctx := rt.NewContext()
+	defer ctx.Free()
+
+	// runs `fn` on a new goroutine, and reports whether it panicked.
+	panics_elsewhere := func(fn func()) bool {
+		panicked := make(chan bool)
+		go func() {
+			defer func() { panicked <- recover() != nil }()
+			fn()
+		}()
+		return <-panicked
+	}
+
+	test_name := "NewRuntime - sequential handoff"
+	t.Run(test_name, func(t *testing.T) {
+		obj := ctx.NewObject()
+		defer obj.Free()
+		handover := func() {
+			obj.Set("key", ctx.NewString("value"))
+		}
+		if panics_elsewhere(handover) {
+			t.Errorf(`[panic check]: expected an idle runtime to be usable by another goroutine, for test: "%s"`, test_name)
+		}
+		key := obj.Get("key")
+		defer key.Free()
+		if got := key.ToString(); got != "value" {
+			t.Errorf(`[value check]: expected value: "%s", got: "%s", for test: "%s"`, "value", got, test_name)
+		}
+	})
+
+	test_name = "NewRuntime - concurrent use"
+	t.Run(test_name, func(t *testing.T) {
+		if !js.LeakDetection {
+			t.Skip(`the ownership assertion is only compiled in with the "quiccjs_debug" build tag`)
+		}
+		obj := ctx.NewObject()
+		defer obj.Free()
+		// the misuses are attempted while the runtime is executing the script below on behalf of this goroutine.
+		misuses := map[string]func(){
+			"IsObject": func() { obj.IsObject() },
+			"Get":      func() { obj.Get("key") },
+			"Eval":     func() { ctx.Eval(`1`) },
+		}
+		meanwhile := ctx.NewFunction("meanwhile", 0, func(ctx *js.Context, this *js.Value, args []*js.Value) (*js.Value, error) {
+			for name, misuse := range misuses {
+				if !panics_elsewhere(misuse) {
+					t.Errorf(`[panic check]: expected "%s" to panic during another goroutine's execution, for test: "%s"`, name, test_name)
+				}
+			}
+			return nil, nil
+		})
+		defer meanwhile.Free()
+		result := meanwhile.Call(nil)
+		if result.IsException() {
+			t.Fatalf(`[call check]: unexpected exception, for test: "%s"`, test_name)
+		}
+		result.Free()
+		// once the execution is over, the runtime may be handed over again.
+		if panics_elsewhere(func() { obj.IsObject() }) {
+			t.Errorf(`[panic check]: expected the runtime to be released after the execution, for test: "%s"`, test_name)
+		}
+	})
+}
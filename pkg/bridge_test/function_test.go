@@ -0,0 +1,86 @@
+// this file contains tests for `function.go` file under the [bridge] package.
+
+package bridge_test
+
+import (
+	errors "errors"
+	runtime "runtime"
+	testing "testing"
+	weak "weak"
+
+	js "github.com/oazmi/quiccjs/pkg/bridge"
+)
+
+func TestFunction_NewFunction(t *testing.T) {
+	rt := js.NewRuntime()
+	defer rt.Free()
+	ctx := rt.NewContext()
+	defer ctx.Free()
+
+	test_name := "NewFunction - called from javascript"
+	t.Run(test_name, func(t *testing.T) {
+		add := ctx.NewFunction("add", 2, func(ctx *js.Context, this *js.Value, args []*js.Value) (*js.Value, error) {
+			return ctx.NewInt64(args[0].ToInt64() + args[1].ToInt64()), nil
+		})
+		ctx.GetGlobalThis().Set("add", add)
+		result, err := ctx.Eval(`add(40, 2)`)
+		if err != nil {
+			t.Fatalf(`[eval check ]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		if got := result.ToInt64(); got != 42 {
+			t.Errorf(`[value check]: expected value: "%d", got: "%d", for test: "%s"`, 42, got, test_name)
+		}
+		fn_name, _ := ctx.Eval(`add.name + "/" + add.length`)
+		defer fn_name.Free()
+		if got := fn_name.ToString(); got != "add/2" {
+			t.Errorf(`[value check]: expected function name and length: "%s", got: "%s", for test: "%s"`, "add/2", got, test_name)
+		}
+	})
+
+	test_name = "NewFunction - missing arguments are undefined"
+	t.Run(test_name, func(t *testing.T) {
+		fun := ctx.NewFunction("isUndefined", 1, func(ctx *js.Context, this *js.Value, args []*js.Value) (*js.Value, error) {
+			return ctx.NewBool(args[0].IsUndefined()), nil
+		})
+		defer fun.Free()
+		result := fun.Call(nil)
+		if !result.ToBool() {
+			t.Errorf(`[value check]: expected the padded argument to be "undefined", for test: "%s"`, test_name)
+		}
+	})
+
+	test_name = "NewFunction - go errors are thrown"
+	t.Run(test_name, func(t *testing.T) {
+		fail := ctx.NewFunction("fail", 0, func(ctx *js.Context, this *js.Value, args []*js.Value) (*js.Value, error) {
+			return nil, errors.New("go says no")
+		})
+		ctx.GetGlobalThis().Set("fail", fail)
+		result, err := ctx.Eval(`try { fail(); "not thrown" } catch (err) { err.message }`)
+		if err != nil {
+			t.Fatalf(`[eval check ]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		defer result.Free()
+		if got := result.ToString(); got != "go says no" {
+			t.Errorf(`[value check]: expected error message: "%s", got: "%s", for test: "%s"`, "go says no", got, test_name)
+		}
+	})
+
+	test_name = "NewFunction - released once garbage collected"
+	t.Run(test_name, func(t *testing.T) {
+		// the closure of a temporary function captures `payload`, which must become unreachable once the function is collected by quickjs.
+		spawn := func() weak.Pointer[[64]byte] {
+			payload := new([64]byte)
+			fun := ctx.NewFunction("temporary", 0, func(ctx *js.Context, this *js.Value, args []*js.Value) (*js.Value, error) {
+				return ctx.NewInt32(int32(payload[0])), nil
+			})
+			fun.Free()
+			return weak.Make(payload)
+		}
+		payload := spawn()
+		rt.RunGC()
+		runtime.GC()
+		if payload.Value() != nil {
+			t.Errorf(`[release check]: expected the go-function to be released, for test: "%s"`, test_name)
+		}
+	})
+}
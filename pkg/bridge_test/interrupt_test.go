@@ -0,0 +1,107 @@
+// this file contains tests for `interrupt.go` file under the [bridge] package.
+
+package bridge_test
+
+import (
+	context "context"
+	errors "errors"
+	testing "testing"
+	time "time"
+
+	js "github.com/oazmi/quiccjs/pkg/bridge"
+)
+
+func TestInterrupt(t *testing.T) {
+	rt := js.NewRuntime()
+	defer rt.Free()
+	ctx := rt.NewContext()
+	defer ctx.Free()
+
+	test_name := "EvalContext - deadline exceeded"
+	t.Run(test_name, func(t *testing.T) {
+		goctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		// the interruption is uncatchable, so the `catch` block must not swallow it.
+		_, err := ctx.EvalContext(goctx, `try { while (true) {} } catch (e) {}`)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf(`[error check]: expected "context.DeadlineExceeded", got: "%v", for test: "%s"`, err, test_name)
+		}
+	})
+
+	test_name = "Interrupt - from another goroutine"
+	t.Run(test_name, func(t *testing.T) {
+		defer interruptRepeatedly(rt)()
+		_, err := ctx.Eval(`while (true) {}`)
+		if !errors.Is(err, js.ErrInterrupted) {
+			t.Errorf(`[error check]: expected "ErrInterrupted", got: "%v", for test: "%s"`, err, test_name)
+		}
+	})
+
+	test_name = "Interrupt - next execution is unaffected"
+	t.Run(test_name, func(t *testing.T) {
+		// nothing is running, so the request is ignored.
+		rt.Interrupt()
+		// a request made by a script that finishes before the interrupt handler is polled is dropped along with the execution.
+		interrupt := ctx.NewFunction("interrupt", 0, func(ctx *js.Context, this *js.Value, args []*js.Value) (*js.Value, error) {
+			rt.Interrupt()
+			return nil, nil
+		})
+		ctx.GetGlobalThis().Set("interrupt", interrupt)
+		// this execution may or may not get interrupted, depending on whether quickjs happens to poll the handler before it ends.
+		first, _ := ctx.Eval(`interrupt(); "done"`)
+		first.Free()
+		result, err := ctx.Eval(`let unaffected_counter = 0; while (unaffected_counter < 100000) { unaffected_counter++ }; unaffected_counter`)
+		if err != nil {
+			t.Fatalf(`[eval check]: expected no pending interruption, got error: "%v", for test: "%s"`, err, test_name)
+		}
+		defer result.Free()
+		if got := result.ToInt64(); got != 100000 {
+			t.Errorf(`[value check]: expected "100000", got: "%d", for test: "%s"`, got, test_name)
+		}
+	})
+
+	test_name = "SetInstructionBudget - exhausted"
+	t.Run(test_name, func(t *testing.T) {
+		rt.SetInstructionBudget(10)
+		_, err := ctx.Eval(`while (true) {}`)
+		rt.SetInstructionBudget(-1)
+		if !errors.Is(err, js.ErrInstructionBudget) {
+			t.Errorf(`[error check]: expected "ErrInstructionBudget", got: "%v", for test: "%s"`, err, test_name)
+		}
+		result, err := ctx.Eval(`let sum = 0; for (let i = 0; i < 100000; i++) { sum += i }; sum`)
+		if err != nil || result.ToInt64() != 4999950000 {
+			t.Fatalf(`[eval check]: expected "4999950000", got error: "%v", for test: "%s"`, err, test_name)
+		}
+		result.Free()
+	})
+
+	test_name = "CallContext - already cancelled"
+	t.Run(test_name, func(t *testing.T) {
+		fn, _ := ctx.Eval(`() => 1`)
+		defer fn.Free()
+		goctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := fn.CallContext(goctx, nil); !errors.Is(err, context.Canceled) {
+			t.Errorf(`[error check]: expected "context.Canceled", got: "%v", for test: "%s"`, err, test_name)
+		}
+	})
+}
+
+// keep on interrupting `rt` from another goroutine until the returned `stop` function is called,
+// since the requests that arrive while the runtime is idle (such as before the tested script starts running) are ignored.
+func interruptRepeatedly(rt *js.Runtime) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(20 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				rt.Interrupt()
+			}
+		}
+	}()
+	return func() { close(done) }
+}
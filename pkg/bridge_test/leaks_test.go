@@ -0,0 +1,78 @@
+// this file contains tests for `leaks.go` file under the [bridge] package.
+// these tests only run when built with the `quiccjs_debug` tag, since the leak detector is not compiled in otherwise.
+
+package bridge_test
+
+import (
+	testing "testing"
+
+	js "github.com/oazmi/quiccjs/pkg/bridge"
+	bridgetest "github.com/oazmi/quiccjs/pkg/bridgetest"
+)
+
+func TestLeaks(t *testing.T) {
+	if !js.LeakDetection {
+		t.Skip(`the leak detector requires the "quiccjs_debug" build tag`)
+	}
+	rt := js.NewRuntime()
+	defer rt.Free()
+
+	// runs `fn` inside of a fresh context, and returns the leaks that get reported once the context is freed.
+	collect_leaks := func(fn func(ctx *js.Context)) []js.Leak {
+		ctx := rt.NewContext()
+		var leaks []js.Leak
+		ctx.OnLeak(func(reported []js.Leak) { leaks = reported })
+		fn(ctx)
+		ctx.Free()
+		return leaks
+	}
+
+	test_name := "no leaks"
+	t.Run(test_name, func(t *testing.T) {
+		ctx := rt.NewContext()
+		bridgetest.CheckLeaks(t, ctx)
+		defer ctx.Free()
+		obj := ctx.NewObject()
+		obj.Set("name", ctx.NewString("value"))
+		name := obj.Get("name")
+		name.Free()
+		obj.Free()
+		atom := ctx.NewAtom("key")
+		atom.Free()
+	})
+
+	test_name = "unfreed value"
+	t.Run(test_name, func(t *testing.T) {
+		leaks := collect_leaks(func(ctx *js.Context) {
+			ctx.NewObject()
+		})
+		if len(leaks) != 1 || leaks[0].Kind != "Value" || leaks[0].Problem != "never freed" {
+			t.Errorf(`[leak check]: expected one unfreed value, got: "%v", for test: "%s"`, leaks, test_name)
+		}
+	})
+
+	test_name = "unfreed atom"
+	t.Run(test_name, func(t *testing.T) {
+		leaks := collect_leaks(func(ctx *js.Context) {
+			ctx.NewAtom("unfreed")
+		})
+		if len(leaks) != 1 || leaks[0].Kind != "Atom" {
+			t.Errorf(`[leak check]: expected one unfreed atom, got: "%v", for test: "%s"`, leaks, test_name)
+		}
+	})
+
+	test_name = "double free"
+	t.Run(test_name, func(t *testing.T) {
+		leaks := collect_leaks(func(ctx *js.Context) {
+			arr := ctx.NewArray()
+			// the extra reference taken by the duplicate keeps the second free from corrupting the memory.
+			// in exchange, the duplicate itself gets reported as unfreed.
+			arr.Dupe()
+			arr.Free()
+			arr.Free()
+		})
+		if len(leaks) != 2 || leaks[0].Problem == "never freed" || leaks[1].Problem != "never freed" {
+			t.Errorf(`[leak check]: expected a double free, followed by an unfreed value, got: "%v", for test: "%s"`, leaks, test_name)
+		}
+	})
+}
@@ -0,0 +1,75 @@
+// this file contains tests for `limits.go` file under the [bridge] package.
+
+package bridge_test
+
+import (
+	errors "errors"
+	testing "testing"
+
+	js "github.com/oazmi/quiccjs/pkg/bridge"
+)
+
+func TestLimits(t *testing.T) {
+	rt := js.NewRuntime()
+	defer rt.Free()
+	ctx := rt.NewContext()
+	defer ctx.Free()
+
+	test_name := "SetMaxStackSize - unbounded recursion"
+	t.Run(test_name, func(t *testing.T) {
+		rt.SetMaxStackSize(256 * 1024)
+		_, err := ctx.Eval(`function recurse() { return recurse() + 1 }; recurse()`)
+		if !errors.Is(err, js.ErrStackOverflow) {
+			t.Errorf(`[error check]: expected "ErrStackOverflow", got: "%v", for test: "%s"`, err, test_name)
+		}
+		if errors.Is(err, js.ErrOutOfMemory) {
+			t.Errorf(`[error check]: did not expect "ErrOutOfMemory", for test: "%s"`, test_name)
+		}
+	})
+
+	test_name = "SetMemoryLimit - unbounded allocation"
+	t.Run(test_name, func(t *testing.T) {
+		rt.SetMemoryLimit(16 * 1024 * 1024)
+		_, err := ctx.Eval(`(() => { const chunks = []; while (true) { chunks.push(new Array(100000).fill(1)) } })()`)
+		rt.SetMemoryLimit(0)
+		rt.RunGC()
+		if !errors.Is(err, js.ErrOutOfMemory) {
+			t.Errorf(`[error check]: expected "ErrOutOfMemory", got: "%v", for test: "%s"`, err, test_name)
+		}
+		// the context must remain usable once the limit has been lifted.
+		result, err := ctx.Eval(`1 + 1`)
+		if err != nil || result.ToInt32() != 2 {
+			t.Fatalf(`[eval check]: expected "2", got error: "%v", for test: "%s"`, err, test_name)
+		}
+		result.Free()
+	})
+
+	test_name = "user thrown InternalError"
+	t.Run(test_name, func(t *testing.T) {
+		_, err := ctx.Eval(`throw new Error("out of memory")`)
+		if errors.Is(err, js.ErrOutOfMemory) {
+			t.Errorf(`[error check]: did not expect "ErrOutOfMemory" for a plain "Error", for test: "%s"`, test_name)
+		}
+		rt.SetMemoryLimit(16 * 1024 * 1024)
+		defer rt.SetMemoryLimit(0)
+		for _, message := range []string{"out of memory", "interrupted"} {
+			_, err := ctx.Eval(`throw new InternalError("` + message + `")`)
+			var js_err *js.Error
+			if !errors.As(err, &js_err) || js_err.Name != "InternalError" || js_err.Message != message {
+				t.Fatalf(`[error check]: expected an "InternalError: %s", got: "%v", for test: "%s"`, message, err, test_name)
+			}
+			if errors.Is(err, js.ErrOutOfMemory) || errors.Is(err, js.ErrStackOverflow) || errors.Is(err, js.ErrInterrupted) {
+				t.Errorf(`[error check]: did not expect a sentinel error for a script-thrown "%s", for test: "%s"`, message, test_name)
+			}
+		}
+		// quickjs leaves no trace of its stack overflows, so they can only be recognized by their name and message.
+		_, err = ctx.Eval(`throw new InternalError("stack overflow")`)
+		if !errors.Is(err, js.ErrStackOverflow) {
+			t.Errorf(`[error check]: expected "ErrStackOverflow" for any "InternalError: stack overflow", got: "%v", for test: "%s"`, err, test_name)
+		}
+		_, err = ctx.Eval(`throw null`)
+		if errors.Is(err, js.ErrOutOfMemory) {
+			t.Errorf(`[error check]: did not expect "ErrOutOfMemory" for a thrown "null", for test: "%s"`, test_name)
+		}
+	})
+}
@@ -0,0 +1,157 @@
+// this file contains tests for `loop.go` file under the [bridge] package.
+
+package bridge_test
+
+import (
+	context "context"
+	errors "errors"
+	strings "strings"
+	testing "testing"
+	time "time"
+
+	js "github.com/oazmi/quiccjs/pkg/bridge"
+)
+
+func TestLoop_RunLoop(t *testing.T) {
+	rt := js.NewRuntime()
+	defer rt.Free()
+	ctx := rt.NewContext()
+	defer ctx.Free()
+
+	test_name := "RunLoop - await chain"
+	t.Run(test_name, func(t *testing.T) {
+		promise, err := ctx.EvalAsync(`globalThis.result = 0; await null; await Promise.resolve(); result = 42;`)
+		if err != nil {
+			t.Fatalf(`[eval check ]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		defer promise.Free()
+		if err := rt.RunLoop(context.Background()); err != nil {
+			t.Fatalf(`[loop check ]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		result := ctx.GetGlobalThis().Get("result")
+		if got := result.ToInt32(); got != 42 {
+			t.Errorf(`[value check]: expected value: "%d", got: "%d", for test: "%s"`, 42, got, test_name)
+		}
+	})
+
+	test_name = "RunLoop - macrotask from a goroutine"
+	t.Run(test_name, func(t *testing.T) {
+		promise, resolve, _ := ctx.NewPromise()
+		ctx.GetGlobalThis().Set("fromGo", promise)
+		complete := rt.Reserve()
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			complete(func() error {
+				resolve(ctx.NewString("hello from a goroutine"))
+				return nil
+			})
+		}()
+		async_result, err := ctx.EvalAsync(`globalThis.message = await fromGo;`)
+		if err != nil {
+			t.Fatalf(`[eval check ]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		defer async_result.Free()
+		if err := rt.RunLoop(context.Background()); err != nil {
+			t.Fatalf(`[loop check ]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		message := ctx.GetGlobalThis().Get("message")
+		defer message.Free()
+		if got := message.ToString(); got != "hello from a goroutine" {
+			t.Errorf(`[value check]: expected value: "%s", got: "%s", for test: "%s"`, "hello from a goroutine", got, test_name)
+		}
+	})
+
+	test_name = "RunLoop - unhandled rejection"
+	t.Run(test_name, func(t *testing.T) {
+		handled, err := ctx.Eval(`const handled = Promise.reject(new Error("handled")); handled.catch(() => {}); handled`)
+		if err != nil {
+			t.Fatalf(`[eval check ]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		defer handled.Free()
+		if err := rt.RunLoop(context.Background()); err != nil {
+			t.Fatalf(`[loop check ]: unexpected error for a handled rejection: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		unhandled, err := ctx.Eval(`Promise.reject(new Error("nobody catches me"))`)
+		if err != nil {
+			t.Fatalf(`[eval check ]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		defer unhandled.Free()
+		if err := rt.RunLoop(context.Background()); err == nil || !strings.Contains(err.Error(), "nobody catches me") {
+			t.Errorf(`[loop check ]: expected the unhandled rejection error, got: "%v", for test: "%s"`, err, test_name)
+		}
+	})
+
+	test_name = "RunLoop - cancellation"
+	t.Run(test_name, func(t *testing.T) {
+		complete := rt.Reserve()
+		defer complete(nil)
+		goctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if err := rt.RunLoop(goctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf(`[loop check ]: expected "context.DeadlineExceeded", got: "%v", for test: "%s"`, err, test_name)
+		}
+	})
+}
+
+func TestLoop_Await(t *testing.T) {
+	rt := js.NewRuntime()
+	defer rt.Free()
+	ctx := rt.NewContext()
+	defer ctx.Free()
+	async_fn, err := ctx.Eval(`(async (x) => { await null; if (x < 0) { throw new RangeError("negative input") } return x * 2 })`)
+	if err != nil {
+		t.Fatalf(`[eval check ]: unexpected error: "%s"`, err.Error())
+	}
+	defer async_fn.Free()
+
+	test_name := "Await - fulfilled"
+	t.Run(test_name, func(t *testing.T) {
+		promise := async_fn.Call(nil, ctx.NewInt32(21))
+		defer promise.Free()
+		result, err := promise.Await(context.Background())
+		if err != nil {
+			t.Fatalf(`[await check]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		defer result.Free()
+		if got := result.ToInt32(); got != 42 {
+			t.Errorf(`[value check]: expected value: "%d", got: "%d", for test: "%s"`, 42, got, test_name)
+		}
+	})
+
+	test_name = "Await - rejected"
+	t.Run(test_name, func(t *testing.T) {
+		promise := async_fn.Call(nil, ctx.NewInt32(-1))
+		defer promise.Free()
+		_, err := promise.Await(context.Background())
+		var js_err *js.Error
+		if !errors.As(err, &js_err) || js_err.Name != "RangeError" || js_err.Message != "negative input" {
+			t.Fatalf(`[error check]: expected a "RangeError" with the message "negative input", got: "%v", for test: "%s"`, err, test_name)
+		}
+		// the rejection has been consumed by `Await`, so the event loop must not report it as unhandled.
+		if err := rt.RunLoop(context.Background()); err != nil {
+			t.Errorf(`[loop check ]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+	})
+
+	test_name = "Await - timeout"
+	t.Run(test_name, func(t *testing.T) {
+		promise, resolve, _ := ctx.NewPromise()
+		defer promise.Free()
+		complete := rt.Reserve()
+		defer complete(func() error { resolve(nil); return nil })
+		goctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if _, err := promise.Await(goctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf(`[error check]: expected "context.DeadlineExceeded", got: "%v", for test: "%s"`, err, test_name)
+		}
+	})
+
+	test_name = "Await - stalled"
+	t.Run(test_name, func(t *testing.T) {
+		promise, _, _ := ctx.NewPromise()
+		defer promise.Free()
+		if _, err := promise.Await(context.Background()); !errors.Is(err, js.ErrAwaitStalled) {
+			t.Errorf(`[error check]: expected "ErrAwaitStalled", got: "%v", for test: "%s"`, err, test_name)
+		}
+	})
+}
@@ -0,0 +1,175 @@
+// this file contains tests for `marshal.go` file under the [bridge] package.
+
+package bridge_test
+
+import (
+	bytes "bytes"
+	errors "errors"
+	big "math/big"
+	testing "testing"
+	time "time"
+
+	js "github.com/oazmi/quiccjs/pkg/bridge"
+	bridgetest "github.com/oazmi/quiccjs/pkg/bridgetest"
+)
+
+type marshalTestItem struct {
+	Name  string `js:"name"`
+	Count int    `js:"count,omitempty"`
+}
+
+type marshalTestConfig struct {
+	Title    string
+	Items    []marshalTestItem `js:"items"`
+	Labels   map[string]int
+	Payload  []byte
+	Big      *big.Int
+	Created  time.Time
+	Secret   string `js:"-"`
+	Optional *string
+}
+
+type marshalTestNode struct {
+	Name     string
+	Next     *marshalTestNode
+	Children map[string]any
+	Note     string `js:"note,omitempty,other"`
+}
+
+func TestMarshal_RoundTrip(t *testing.T) {
+	rt := js.NewRuntime()
+	defer rt.Free()
+	ctx := rt.NewContext()
+	defer ctx.Free()
+
+	test_bigint, _ := (&big.Int{}).SetString("123456789012345678901234567890", 10)
+	original := marshalTestConfig{
+		Title:   "config",
+		Items:   []marshalTestItem{{Name: "a", Count: 1}, {Name: "b"}},
+		Labels:  map[string]int{"x": 1, "y": 2},
+		Payload: []byte{1, 2, 3},
+		Big:     test_bigint,
+		Created: time.UnixMilli(1700000000000),
+		Secret:  "hidden",
+	}
+
+	test_name := "Marshal - javascript shape"
+	t.Run(test_name, func(t *testing.T) {
+		val, err := ctx.Marshal(original)
+		if err != nil {
+			t.Fatalf(`[marshal check]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		ctx.GetGlobalThis().Set("config", val)
+		summary, err := ctx.Eval(`[
+			config.title,
+			config.items.length,
+			"count" in config.items[1],
+			config.labels.y,
+			config.payload instanceof Uint8Array,
+			typeof config.big,
+			config.created instanceof Date,
+			"secret" in config,
+			config.optional,
+		].join(",")`)
+		if err != nil {
+			t.Fatalf(`[eval check ]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		defer summary.Free()
+		expected := "config,2,false,2,true,bigint,true,false,"
+		if got := summary.ToString(); got != expected {
+			t.Errorf(`[value check]: expected value: "%s", got: "%s", for test: "%s"`, expected, got, test_name)
+		}
+	})
+
+	test_name = "Unmarshal - round trip"
+	t.Run(test_name, func(t *testing.T) {
+		val, err := ctx.Marshal(&original)
+		if err != nil {
+			t.Fatalf(`[marshal check]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		defer val.Free()
+		var decoded marshalTestConfig
+		if err := val.Unmarshal(&decoded); err != nil {
+			t.Fatalf(`[unmarshal check]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		if decoded.Title != original.Title || len(decoded.Items) != 2 || decoded.Items[0] != original.Items[0] || decoded.Labels["y"] != 2 {
+			t.Errorf(`[value check]: expected: "%+v", got: "%+v", for test: "%s"`, original, decoded, test_name)
+		}
+		if !bytes.Equal(decoded.Payload, original.Payload) || decoded.Big.Cmp(original.Big) != 0 || !decoded.Created.Equal(original.Created) {
+			t.Errorf(`[value check]: expected: "%+v", got: "%+v", for test: "%s"`, original, decoded, test_name)
+		}
+		if decoded.Secret != "" || decoded.Optional != nil {
+			t.Errorf(`[value check]: expected hidden and null fields to stay empty, got: "%+v", for test: "%s"`, decoded, test_name)
+		}
+	})
+
+	test_name = "Unmarshal - path qualified errors"
+	t.Run(test_name, func(t *testing.T) {
+		val, err := ctx.Eval(`({ items: [{ name: "a" }, { name: "b" }, { name: "c" }, { name: {} }] })`)
+		if err != nil {
+			t.Fatalf(`[eval check ]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		defer val.Free()
+		var decoded marshalTestConfig
+		err = val.Unmarshal(&decoded)
+		var marshal_err *js.MarshalError
+		if !errors.As(err, &marshal_err) {
+			t.Fatalf(`[error check]: expected a "*MarshalError", got: "%v", for test: "%s"`, err, test_name)
+		}
+		expected := ".items[3].name: expected string, got object"
+		if got := err.Error(); got != expected {
+			t.Errorf(`[error check]: expected error: "%s", got: "%s", for test: "%s"`, expected, got, test_name)
+		}
+	})
+
+	test_name = "Marshal - reference cycles"
+	t.Run(test_name, func(t *testing.T) {
+		node := &marshalTestNode{Name: "loop"}
+		node.Next = node
+		self_map := map[string]any{}
+		self_map["self"] = self_map
+		for path, v := range map[string]any{".next": node, ".children.inner.self": marshalTestNode{Children: map[string]any{"inner": self_map}}} {
+			_, err := ctx.Marshal(v)
+			var marshal_err *js.MarshalError
+			if !errors.As(err, &marshal_err) || marshal_err.Path != path {
+				t.Errorf(`[error check]: expected a "*MarshalError" at "%s", got: "%v", for test: "%s"`, path, err, test_name)
+			}
+		}
+		// the same pointer may appear more than once, as long as it doesn't enclose itself.
+		shared := &marshalTestNode{Name: "shared"}
+		val, err := ctx.Marshal(marshalTestNode{Next: shared, Children: map[string]any{"a": shared, "b": []any{shared, shared}}})
+		if err != nil {
+			t.Fatalf(`[marshal check]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		ctx.GetGlobalThis().Set("node", val)
+		summary, err := ctx.Eval(`[node.next.name, node.children.a.name, node.children.b[1].name, "note" in node].join(",")`)
+		if err != nil {
+			t.Fatalf(`[eval check ]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		defer summary.Free()
+		if got := summary.ToString(); got != "shared,shared,shared,false" {
+			t.Errorf(`[value check]: expected value: "%s", got: "%s", for test: "%s"`, "shared,shared,shared,false", got, test_name)
+		}
+	})
+
+	test_name = "Unmarshal - failed map entries are freed"
+	t.Run(test_name, func(t *testing.T) {
+		if !js.LeakDetection {
+			t.Skip(`the leak detector requires the "quiccjs_debug" build tag`)
+		}
+		leak_ctx := rt.NewContext()
+		bridgetest.CheckLeaks(t, leak_ctx)
+		defer leak_ctx.Free()
+		// the bad entry sits in the middle, so that the entries after it are never unmarshaled.
+		val, err := leak_ctx.Eval(`({ a: 1, b: "not a number", c: { nested: true }, d: [4] })`)
+		if err != nil {
+			t.Fatalf(`[eval check ]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		defer val.Free()
+		var decoded map[string]int
+		var marshal_err *js.MarshalError
+		if err := val.Unmarshal(&decoded); !errors.As(err, &marshal_err) || marshal_err.Path != ".b" {
+			t.Errorf(`[error check]: expected a "*MarshalError" at ".b", got: "%v", for test: "%s"`, err, test_name)
+		}
+	})
+}
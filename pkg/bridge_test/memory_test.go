@@ -0,0 +1,48 @@
+// this file contains tests for `memory.go` file under the [bridge] package.
+
+package bridge_test
+
+import (
+	bytes "bytes"
+	strings "strings"
+	testing "testing"
+
+	js "github.com/oazmi/quiccjs/pkg/bridge"
+)
+
+func TestMemoryUsage(t *testing.T) {
+	rt := js.NewRuntime()
+	defer rt.Free()
+	ctx := rt.NewContext()
+	defer ctx.Free()
+
+	test_name := "MemoryUsage - before and after"
+	t.Run(test_name, func(t *testing.T) {
+		before := rt.MemoryUsage()
+		result, err := ctx.Eval(`globalThis.kept = Array.from({ length: 1000 }, (_, i) => ({ i })); kept.length`)
+		if err != nil {
+			t.Fatalf(`[eval check]: unexpected error: "%v", for test: "%s"`, err, test_name)
+		}
+		result.Free()
+		after := rt.MemoryUsage()
+		if after.ObjectCount < before.ObjectCount+1000 {
+			t.Errorf(`[usage check]: expected at least 1000 more objects, got: "%d" -> "%d", for test: "%s"`, before.ObjectCount, after.ObjectCount, test_name)
+		}
+		if after.MemoryUsedSize <= before.MemoryUsedSize {
+			t.Errorf(`[usage check]: expected the used memory to grow, got: "%d" -> "%d", for test: "%s"`, before.MemoryUsedSize, after.MemoryUsedSize, test_name)
+		}
+	})
+
+	test_name = "DumpMemoryUsage"
+	t.Run(test_name, func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := rt.DumpMemoryUsage(&buf); err != nil {
+			t.Fatalf(`[dump check]: unexpected error: "%v", for test: "%s"`, err, test_name)
+		}
+		for _, row := range []string{"QuickJS memory usage", "memory allocated", "objects", "C functions"} {
+			if !strings.Contains(buf.String(), row) {
+				t.Errorf(`[dump check]: expected the row "%s" in the output:\n%s`, row, buf.String())
+			}
+		}
+	})
+}
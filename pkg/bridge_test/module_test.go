@@ -0,0 +1,269 @@
+// this file contains tests for `module.go` file under the [bridge] package.
+
+package bridge_test
+
+import (
+	errors "errors"
+	fmt "fmt"
+	os "os"
+	path "path"
+	filepath "path/filepath"
+	strings "strings"
+	testing "testing"
+	fstest "testing/fstest"
+
+	js "github.com/oazmi/quiccjs/pkg/bridge"
+)
+
+// an in-memory module loader, mapping absolute module names to their source code.
+type mapModuleLoader map[string]string
+
+func (loader mapModuleLoader) Normalize(base, specifier string) (string, error) {
+	return path.Join(path.Dir(base), specifier), nil
+}
+
+func (loader mapModuleLoader) Load(name string) (string, error) {
+	source, ok := loader[name]
+	if !ok {
+		return "", fmt.Errorf("no such module")
+	}
+	return source, nil
+}
+
+// a module loader that panics with its `stage` ("normalize" or "load") once it gets there.
+type panickingModuleLoader struct{ stage string }
+
+func (loader panickingModuleLoader) Normalize(base, specifier string) (string, error) {
+	if loader.stage == "normalize" {
+		panic("normalize exploded")
+	}
+	return specifier, nil
+}
+
+func (loader panickingModuleLoader) Load(name string) (string, error) {
+	panic("load exploded")
+}
+
+func TestModule_EvalModule(t *testing.T) {
+	rt := js.NewRuntime()
+	defer rt.Free()
+	ctx := rt.NewContext()
+	defer ctx.Free()
+	rt.SetModuleLoader(mapModuleLoader{
+		"/lib/math.js":   `import { double } from "./double.js"; export const add = (a, b) => double(a + b) / 2;`,
+		"/lib/double.js": `export function double(x) { return x * 2; }`,
+		"/lib/broken.js": `export const = 1;`,
+	})
+
+	test_name := "EvalModule - relative imports"
+	t.Run(test_name, func(t *testing.T) {
+		namespace, err := ctx.EvalModule("/main.js", `import { add } from "./lib/math.js"; export const answer = add(40, 2);`)
+		if err != nil {
+			t.Fatalf(`[eval check ]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		defer namespace.Free()
+		answer := namespace.Get("answer")
+		defer answer.Free()
+		if got := answer.ToInt64(); got != 42 {
+			t.Errorf(`[value check]: expected value: "%d", got: "%d", for test: "%s"`, 42, got, test_name)
+		}
+	})
+
+	test_name = "EvalModule - missing module"
+	t.Run(test_name, func(t *testing.T) {
+		_, err := ctx.EvalModule("/missing.js", `import "./lib/nothing.js";`)
+		if err == nil || !strings.Contains(err.Error(), "no such module") {
+			t.Errorf(`[error check]: expected a "no such module" error, got: "%v", for test: "%s"`, err, test_name)
+		}
+	})
+
+	test_name = "EvalModule - syntax error in dependency"
+	t.Run(test_name, func(t *testing.T) {
+		_, err := ctx.EvalModule("/syntax.js", `import "./lib/broken.js";`)
+		if err == nil || !strings.Contains(err.Error(), "SyntaxError") {
+			t.Errorf(`[error check]: expected a "SyntaxError", got: "%v", for test: "%s"`, err, test_name)
+		}
+	})
+
+	test_name = "EvalModule - thrown during evaluation"
+	t.Run(test_name, func(t *testing.T) {
+		_, err := ctx.EvalModule("/throws.js", `throw new RangeError("bad module");`)
+		if err == nil || !strings.Contains(err.Error(), "bad module") {
+			t.Errorf(`[error check]: expected the module's thrown error, got: "%v", for test: "%s"`, err, test_name)
+		}
+	})
+}
+
+func TestModule_PanickingLoader(t *testing.T) {
+	rt := js.NewRuntime()
+	defer rt.Free()
+	ctx := rt.NewContext()
+	defer ctx.Free()
+
+	for _, stage := range []string{"normalize", "load"} {
+		test_name := "EvalModule - panic during " + stage
+		t.Run(test_name, func(t *testing.T) {
+			rt.SetModuleLoader(panickingModuleLoader{stage: stage})
+			_, err := ctx.EvalModule("/main.js", `import "/dependency.js";`)
+			var panic_err *js.PanicError
+			if !errors.As(err, &panic_err) || panic_err.Value != stage+" exploded" {
+				t.Errorf(`[error check]: expected a "*PanicError" of "%s exploded", got: "%v", for test: "%s"`, stage, err, test_name)
+			}
+		})
+	}
+}
+
+func TestModule_FSModuleLoader(t *testing.T) {
+	loader := js.NewFSModuleLoader(fstest.MapFS{
+		"main.js":                   {Data: []byte(`import { greet } from "./lib/greet"; import { pad } from "pad"; export const text = pad(greet("go"));`)},
+		"lib/greet.mjs":             {Data: []byte(`import { name } from "../lib"; export const greet = (who) => "hello " + who + " from " + name;`)},
+		"lib/index.js":              {Data: []byte(`export const name = "lib";`)},
+		"vendor/pad/index.js":       {Data: []byte(`export const pad = (text) => "[" + text + "]";`)},
+		"vendor/pad/extra/right.js": {Data: []byte(`export const padRight = (text) => text + " ";`)},
+	})
+	loader.ImportMap["pad"] = "/vendor/pad/index.js"
+	loader.ImportMap["pad/"] = "./vendor/pad/"
+	loader.ImportMap["greeter"] = "lib/greet.mjs"
+	loader.ImportMap["extra/"] = "vendor/pad/extra/"
+
+	type testCase struct {
+		base      string
+		specifier string
+		expected  string
+	}
+	tests := []testCase{
+		{base: "/main.js", specifier: "./lib/greet", expected: "/lib/greet.mjs"},
+		{base: "/lib/greet.mjs", specifier: "../lib", expected: "/lib/index.js"},
+		{base: "/lib/greet.mjs", specifier: "/main", expected: "/main.js"},
+		{base: "<eval>", specifier: "./main.js", expected: "/main.js"},
+		{base: "/lib/greet.mjs", specifier: "pad", expected: "/vendor/pad/index.js"},
+		{base: "/lib/greet.mjs", specifier: "pad/extra/right", expected: "/vendor/pad/extra/right.js"},
+		{base: "/vendor/pad/index.js", specifier: "greeter", expected: "/lib/greet.mjs"},
+		{base: "/vendor/pad/index.js", specifier: "extra/right", expected: "/vendor/pad/extra/right.js"},
+	}
+	for _, tt := range tests {
+		test_name := "Normalize - " + tt.specifier + " from " + tt.base
+		t.Run(test_name, func(t *testing.T) {
+			got, err := loader.Normalize(tt.base, tt.specifier)
+			if err != nil {
+				t.Fatalf(`[normalize check]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+			}
+			if got != tt.expected {
+				t.Errorf(`[value check]: expected module name: "%s", got: "%s", for test: "%s"`, tt.expected, got, test_name)
+			}
+		})
+	}
+
+	rejected := []string{"../main.js", "/../main.js", "./lib/../../main.js", "unmapped", "./missing.js"}
+	for _, specifier := range rejected {
+		test_name := "Normalize - rejects " + specifier
+		t.Run(test_name, func(t *testing.T) {
+			if got, err := loader.Normalize("/main.js", specifier); err == nil {
+				t.Errorf(`[error check]: expected an error, got the module name: "%s", for test: "%s"`, got, test_name)
+			}
+		})
+	}
+
+	test_name := "EvalModule - through the loader"
+	t.Run(test_name, func(t *testing.T) {
+		rt := js.NewRuntime()
+		defer rt.Free()
+		ctx := rt.NewContext()
+		defer ctx.Free()
+		rt.SetModuleLoader(loader)
+		namespace, err := ctx.EvalModule("/entry.js", `export { text } from "./main.js";`)
+		if err != nil {
+			t.Fatalf(`[eval check ]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		defer namespace.Free()
+		text := namespace.Get("text")
+		defer text.Free()
+		expected := "[hello go from lib]"
+		if got := text.ToString(); got != expected {
+			t.Errorf(`[value check]: expected value: "%s", got: "%s", for test: "%s"`, expected, got, test_name)
+		}
+	})
+}
+
+func TestModule_NewDirModuleLoader(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.js"), []byte(`export const answer = 42;`), 0o644); err != nil {
+		t.Fatalf(`[setup check]: unexpected error: "%s"`, err.Error())
+	}
+	loader, err := js.NewDirModuleLoader(dir)
+	if err != nil {
+		t.Fatalf(`[loader check]: unexpected error: "%s"`, err.Error())
+	}
+
+	test_name := "NewDirModuleLoader - loads until closed"
+	t.Run(test_name, func(t *testing.T) {
+		name, err := loader.Normalize("/entry.js", "./main")
+		if err != nil || name != "/main.js" {
+			t.Fatalf(`[normalize check]: expected "/main.js", got: "%s" (error: "%v"), for test: "%s"`, name, err, test_name)
+		}
+		if source, err := loader.Load(name); err != nil || source != `export const answer = 42;` {
+			t.Errorf(`[load check]: unexpected source: "%s" (error: "%v"), for test: "%s"`, source, err, test_name)
+		}
+		if err := loader.Close(); err != nil {
+			t.Fatalf(`[close check]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		if _, err := loader.Load(name); err == nil {
+			t.Errorf(`[load check]: expected an error after closing the loader, for test: "%s"`, test_name)
+		}
+	})
+}
+
+func TestModule_RegisterNativeModule(t *testing.T) {
+	rt := js.NewRuntime()
+	defer rt.Free()
+	ctx := rt.NewContext()
+	defer ctx.Free()
+	err := ctx.RegisterNativeModule("go:math", map[string]any{
+		"add":     func(a, b int) int { return a + b },
+		"version": "1.0",
+		"negate": js.GoFunction(func(ctx *js.Context, this *js.Value, args []*js.Value) (*js.Value, error) {
+			return ctx.NewInt64(-args[0].ToInt64()), nil
+		}),
+	})
+	if err != nil {
+		t.Fatalf(`[register check]: unexpected error: "%s"`, err.Error())
+	}
+	source := `import { add, negate, version } from "go:math"; export const result = version + "/" + negate(add(40, 2));`
+
+	test_name := "RegisterNativeModule - without a module loader"
+	t.Run(test_name, func(t *testing.T) {
+		namespace, err := ctx.EvalModule("/main.js", source)
+		if err != nil {
+			t.Fatalf(`[eval check ]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		defer namespace.Free()
+		result := namespace.Get("result")
+		defer result.Free()
+		if got := result.ToString(); got != "1.0/-42" {
+			t.Errorf(`[value check]: expected value: "%s", got: "%s", for test: "%s"`, "1.0/-42", got, test_name)
+		}
+	})
+
+	test_name = "RegisterNativeModule - resolved before the module loader"
+	t.Run(test_name, func(t *testing.T) {
+		rt.SetModuleLoader(mapModuleLoader{"/go:math": `export const version = "shadowed";`})
+		defer rt.SetModuleLoader(nil)
+		namespace, err := ctx.EvalModule("/main2.js", source)
+		if err != nil {
+			t.Fatalf(`[eval check ]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		defer namespace.Free()
+		result := namespace.Get("result")
+		defer result.Free()
+		if got := result.ToString(); got != "1.0/-42" {
+			t.Errorf(`[value check]: expected value: "%s", got: "%s", for test: "%s"`, "1.0/-42", got, test_name)
+		}
+	})
+
+	test_name = "RegisterNativeModule - duplicate name"
+	t.Run(test_name, func(t *testing.T) {
+		if err := ctx.RegisterNativeModule("go:math", nil); err == nil {
+			t.Errorf(`[error check]: expected an error for a duplicate module name, for test: "%s"`, test_name)
+		}
+	})
+}
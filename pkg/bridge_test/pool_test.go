@@ -0,0 +1,183 @@
+// this file contains tests for `pool.go` file under the [bridge] package.
+
+package bridge_test
+
+import (
+	context "context"
+	errors "errors"
+	sync "sync"
+	testing "testing"
+
+	js "github.com/oazmi/quiccjs/pkg/bridge"
+)
+
+// evaluate `code` and return its result as an integer.
+func evalInt(ctx *js.Context, code string) (int64, error) {
+	val, err := ctx.Eval(code)
+	if err != nil {
+		return 0, err
+	}
+	defer val.Free()
+	return val.ToInt64(), nil
+}
+
+func TestPool(t *testing.T) {
+	goctx := context.Background()
+
+	test_name := "Do - init scripts and functions, in parallel"
+	t.Run(test_name, func(t *testing.T) {
+		pool, err := js.NewPool(js.PoolOptions{
+			Size: 4,
+			Functions: map[string]js.GoFunction{
+				"triple": func(ctx *js.Context, this *js.Value, args []*js.Value) (*js.Value, error) {
+					return ctx.NewInt64(args[0].ToInt64() * 3), nil
+				},
+			},
+			InitScripts: []string{`function double(x) { return x * 2 }`},
+		})
+		if err != nil {
+			t.Fatalf(`[pool check]: unexpected error: "%v", for test: "%s"`, err, test_name)
+		}
+		defer pool.Close()
+		var wg sync.WaitGroup
+		for i := range 32 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				var result int64
+				err := pool.Do(goctx, func(ctx *js.Context) (err error) {
+					result, err = evalInt(ctx, `double(triple(7))`)
+					return err
+				})
+				if err != nil || result != 42 {
+					t.Errorf(`[job check]: job %d expected "42", got: "%d" (error: "%v"), for test: "%s"`, i, result, err, test_name)
+				}
+			}()
+		}
+		wg.Wait()
+		stats := pool.Stats()
+		if stats.Completed != 32 || stats.Failed != 0 || stats.QueueDepth != 0 || stats.Running != 0 {
+			t.Errorf(`[stats check]: unexpected stats: "%+v", for test: "%s"`, stats, test_name)
+		}
+	})
+
+	test_name = "Do - recycled after MaxJobs"
+	t.Run(test_name, func(t *testing.T) {
+		pool, err := js.NewPool(js.PoolOptions{Size: 1, MaxJobs: 2})
+		if err != nil {
+			t.Fatalf(`[pool check]: unexpected error: "%v", for test: "%s"`, err, test_name)
+		}
+		defer pool.Close()
+		counts := []int64{}
+		for range 3 {
+			pool.Do(goctx, func(ctx *js.Context) error {
+				count, err := evalInt(ctx, `globalThis.count = (globalThis.count ?? 0) + 1`)
+				counts = append(counts, count)
+				return err
+			})
+		}
+		if len(counts) != 3 || counts[0] != 1 || counts[1] != 2 || counts[2] != 1 {
+			t.Errorf(`[state check]: expected "[1 2 1]", got: "%v", for test: "%s"`, counts, test_name)
+		}
+		if recycled := pool.Stats().Recycled; recycled != 1 {
+			t.Errorf(`[stats check]: expected "1" recycling, got: "%d", for test: "%s"`, recycled, test_name)
+		}
+	})
+
+	test_name = "Do - recycled after running out of memory"
+	t.Run(test_name, func(t *testing.T) {
+		pool, err := js.NewPool(js.PoolOptions{Size: 1, MemoryLimit: 4 << 20})
+		if err != nil {
+			t.Fatalf(`[pool check]: unexpected error: "%v", for test: "%s"`, err, test_name)
+		}
+		defer pool.Close()
+		err = pool.Do(goctx, func(ctx *js.Context) error {
+			_, err := ctx.Eval(`globalThis.hog = []; while (true) { hog.push(new Array(1024).fill(0)) }`)
+			return err
+		})
+		if !errors.Is(err, js.ErrOutOfMemory) {
+			t.Fatalf(`[error check]: expected "ErrOutOfMemory", got: "%v", for test: "%s"`, err, test_name)
+		}
+		var has_hog int64
+		err = pool.Do(goctx, func(ctx *js.Context) (err error) {
+			has_hog, err = evalInt(ctx, `"hog" in globalThis ? 1 : 0`)
+			return err
+		})
+		if err != nil || has_hog != 0 || pool.Stats().Recycled != 1 {
+			t.Errorf(`[recycle check]: expected a fresh runtime, got error: "%v", for test: "%s"`, err, test_name)
+		}
+	})
+
+	test_name = "Do - recycled based on the runtime's state rather than the job's error"
+	t.Run(test_name, func(t *testing.T) {
+		pool, err := js.NewPool(js.PoolOptions{Size: 1, MemoryLimit: 4 << 20})
+		if err != nil {
+			t.Fatalf(`[pool check]: unexpected error: "%v", for test: "%s"`, err, test_name)
+		}
+		defer pool.Close()
+		err = pool.Do(goctx, func(ctx *js.Context) error {
+			_, err := ctx.Eval(`throw new InternalError("out of memory")`)
+			return err
+		})
+		if err == nil || errors.Is(err, js.ErrOutOfMemory) || pool.Stats().Recycled != 0 {
+			t.Errorf(`[recycle check]: expected a script-thrown error to keep the runtime, got error: "%v", for test: "%s"`, err, test_name)
+		}
+		err = pool.Do(goctx, func(ctx *js.Context) error {
+			result, err := ctx.Eval(`try { const hog = []; while (true) { hog.push(new Array(1024).fill(0)) } } catch { "swallowed" }`)
+			result.Free()
+			return err
+		})
+		if err != nil || pool.Stats().Recycled != 1 {
+			t.Errorf(`[recycle check]: expected a swallowed out of memory error to recycle the runtime, got error: "%v", for test: "%s"`, err, test_name)
+		}
+	})
+
+	test_name = "Do - panic and cancelled context"
+	t.Run(test_name, func(t *testing.T) {
+		pool, err := js.NewPool(js.PoolOptions{Size: 1})
+		if err != nil {
+			t.Fatalf(`[pool check]: unexpected error: "%v", for test: "%s"`, err, test_name)
+		}
+		defer pool.Close()
+		var panic_err *js.PanicError
+		err = pool.Do(goctx, func(ctx *js.Context) error { panic("boom") })
+		if !errors.As(err, &panic_err) || panic_err.Value != "boom" {
+			t.Errorf(`[panic check]: expected a "*PanicError" of "boom", got: "%v", for test: "%s"`, err, test_name)
+		}
+		cancelled_ctx, cancel := context.WithCancel(goctx)
+		cancel()
+		err = pool.Do(cancelled_ctx, func(ctx *js.Context) error {
+			_, err := ctx.Eval(`while (true) {}`)
+			return err
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf(`[error check]: expected "context.Canceled", got: "%v", for test: "%s"`, err, test_name)
+		}
+		stats := pool.Stats()
+		if stats.Failed > 2 || stats.Recycled != 1 {
+			t.Errorf(`[stats check]: unexpected stats: "%+v", for test: "%s"`, stats, test_name)
+		}
+	})
+
+	test_name = "NewPool - failing init script"
+	t.Run(test_name, func(t *testing.T) {
+		_, err := js.NewPool(js.PoolOptions{Size: 2, InitScripts: []string{`throw new Error("broken")`}})
+		var js_err *js.Error
+		if !errors.As(err, &js_err) || js_err.Message != "broken" {
+			t.Errorf(`[error check]: expected the "broken" error, got: "%v", for test: "%s"`, err, test_name)
+		}
+	})
+
+	test_name = "Close - rejects new jobs"
+	t.Run(test_name, func(t *testing.T) {
+		pool, err := js.NewPool(js.PoolOptions{Size: 1})
+		if err != nil {
+			t.Fatalf(`[pool check]: unexpected error: "%v", for test: "%s"`, err, test_name)
+		}
+		pool.Close()
+		err = pool.Do(goctx, func(ctx *js.Context) error { return nil })
+		if !errors.Is(err, js.ErrPoolClosed) {
+			t.Errorf(`[error check]: expected "ErrPoolClosed", got: "%v", for test: "%s"`, err, test_name)
+		}
+	})
+}
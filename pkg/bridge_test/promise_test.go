@@ -0,0 +1,85 @@
+// this file contains tests for `promise.go` file under the [bridge] package.
+
+package bridge_test
+
+import (
+	errors "errors"
+	testing "testing"
+
+	js "github.com/oazmi/quiccjs/pkg/bridge"
+)
+
+func TestPromise_NewPromise(t *testing.T) {
+	rt := js.NewRuntime()
+	defer rt.Free()
+	ctx := rt.NewContext()
+	defer ctx.Free()
+
+	test_name := "NewPromise - resolve"
+	t.Run(test_name, func(t *testing.T) {
+		promise, resolve, reject := ctx.NewPromise()
+		defer promise.Free()
+		if !promise.IsPromise() || promise.PromiseState() != js.PromisePending {
+			t.Fatalf(`[state check]: expected a pending promise, for test: "%s"`, test_name)
+		}
+		resolve(ctx.NewString("done"))
+		reject(errors.New("ignored, since the promise has already been settled"))
+		if got := promise.PromiseState(); got != js.PromiseFulfilled {
+			t.Fatalf(`[state check]: expected state: "%d", got: "%d", for test: "%s"`, js.PromiseFulfilled, got, test_name)
+		}
+		result := promise.PromiseResult()
+		defer result.Free()
+		if got := result.ToString(); got != "done" {
+			t.Errorf(`[value check]: expected value: "%s", got: "%s", for test: "%s"`, "done", got, test_name)
+		}
+	})
+
+	test_name = "NewPromise - reject"
+	t.Run(test_name, func(t *testing.T) {
+		promise, _, reject := ctx.NewPromise()
+		defer promise.Free()
+		reject(errors.New("go says no"))
+		if got := promise.PromiseState(); got != js.PromiseRejected {
+			t.Fatalf(`[state check]: expected state: "%d", got: "%d", for test: "%s"`, js.PromiseRejected, got, test_name)
+		}
+		reason := promise.PromiseResult()
+		defer reason.Free()
+		if err := reason.ToError(); err == nil || err.Message != "go says no" {
+			t.Errorf(`[value check]: expected an error with the message: "%s", got: "%v", for test: "%s"`, "go says no", err, test_name)
+		}
+	})
+
+	test_name = "NewPromise - never settled"
+	t.Run(test_name, func(t *testing.T) {
+		// the resolving functions of an unsettled promise must be released when the context is freed, without leaking.
+		promise, _, _ := ctx.NewPromise()
+		promise.Free()
+	})
+
+	test_name = "NewPromise - settled after the context is freed"
+	t.Run(test_name, func(t *testing.T) {
+		// late calls (such as from a callback that outlives the context) must be ignored, without touching the freed context.
+		other_ctx := rt.NewContext()
+		promise, resolve, reject := other_ctx.NewPromise()
+		promise.Free()
+		other_ctx.Free()
+		resolve(nil)
+		reject(errors.New("too late"))
+	})
+
+	test_name = "PromiseState - EvalAsync"
+	t.Run(test_name, func(t *testing.T) {
+		promise, err := ctx.EvalAsync(`throw new TypeError("async failure")`)
+		if err != nil {
+			t.Fatalf(`[eval check ]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		defer promise.Free()
+		if got := promise.PromiseState(); got != js.PromiseRejected {
+			t.Fatalf(`[state check]: expected state: "%d", got: "%d", for test: "%s"`, js.PromiseRejected, got, test_name)
+		}
+		not_a_promise := ctx.NewInt32(1)
+		if got := not_a_promise.PromiseState(); got != js.PromiseInvalid {
+			t.Errorf(`[state check]: expected state: "%d", got: "%d", for test: "%s"`, js.PromiseInvalid, got, test_name)
+		}
+	})
+}
@@ -0,0 +1,109 @@
+// this file contains tests for `scope.go` file under the [bridge] package.
+
+package bridge_test
+
+import (
+	errors "errors"
+	testing "testing"
+
+	js "github.com/oazmi/quiccjs/pkg/bridge"
+	bridgetest "github.com/oazmi/quiccjs/pkg/bridgetest"
+)
+
+func TestScope(t *testing.T) {
+	rt := js.NewRuntime()
+	defer rt.Free()
+	finalized := 0
+	cls := rt.NewClass(js.ClassDefinition{Name: "Tracked", Finalizer: func(data any) { finalized++ }})
+	ctx := rt.NewContext()
+	bridgetest.CheckLeaks(t, ctx)
+	defer ctx.Free()
+	ctx.DefineClass(cls).Free()
+
+	test_name := "WithScope - frees on exit"
+	t.Run(test_name, func(t *testing.T) {
+		finalized = 0
+		sentinel := errors.New("sentinel")
+		err := ctx.WithScope(func(s *js.Scope) error {
+			instance := ctx.NewClassInstance(cls, nil)
+			// explicitly freed and transferred values must not be freed a second time by the scope.
+			ctx.NewObject().Free()
+			holder := ctx.NewObject()
+			holder.Set("instance", instance.Dupe())
+			if finalized != 0 {
+				t.Errorf(`[scope check]: expected nothing to be finalized yet, for test: "%s"`, test_name)
+			}
+			return sentinel
+		})
+		if !errors.Is(err, sentinel) {
+			t.Errorf(`[error check]: expected the error of the callback, got: "%v", for test: "%s"`, err, test_name)
+		}
+		if finalized != 1 {
+			t.Errorf(`[scope check]: expected the instance to be finalized, got: "%d" finalizations, for test: "%s"`, finalized, test_name)
+		}
+	})
+
+	test_name = "Escape - nested scopes"
+	t.Run(test_name, func(t *testing.T) {
+		finalized = 0
+		var escaped *js.Value
+		ctx.WithScope(func(outer *js.Scope) error {
+			ctx.WithScope(func(inner *js.Scope) error {
+				inner.Escape(ctx.NewClassInstance(cls, nil))
+				return nil
+			})
+			if finalized != 0 {
+				t.Errorf(`[scope check]: expected the escaped instance to survive the inner scope, for test: "%s"`, test_name)
+			}
+			escaped = outer.Escape(ctx.NewClassInstance(cls, nil))
+			return nil
+		})
+		if finalized != 1 {
+			t.Errorf(`[scope check]: expected the instance of the outer scope to be finalized, got: "%d" finalizations, for test: "%s"`, finalized, test_name)
+		}
+		if !escaped.IsInstanceOfClass(cls) {
+			t.Errorf(`[scope check]: expected the top-level escaped value to remain usable, for test: "%s"`, test_name)
+		}
+		escaped.Free()
+		if finalized != 2 {
+			t.Errorf(`[scope check]: expected the escaped instance to be finalized once freed, got: "%d" finalizations, for test: "%s"`, finalized, test_name)
+		}
+	})
+
+	test_name = "FreeOnExit - inside of a scope"
+	t.Run(test_name, func(t *testing.T) {
+		finalized = 0
+		ctx.WithScope(func(s *js.Scope) error {
+			ctx.NewClassInstance(cls, nil).FreeOnExit()
+			return nil
+		})
+		if finalized != 0 {
+			t.Errorf(`[scope check]: expected the value to be kept until the context exits, for test: "%s"`, test_name)
+		}
+	})
+
+	test_name = "Detach - a duplicated argument outlives the caller's scope"
+	t.Run(test_name, func(t *testing.T) {
+		finalized = 0
+		var kept, detached *js.Value
+		keep := ctx.NewFunction("keep", 1, func(ctx *js.Context, this *js.Value, args []*js.Value) (*js.Value, error) {
+			// the callback runs outside of the caller's scope, so the duplicate is its own to keep.
+			kept = args[0].Dupe()
+			return nil, nil
+		})
+		defer keep.Free()
+		ctx.WithScope(func(s *js.Scope) error {
+			keep.Call(nil, ctx.NewClassInstance(cls, nil))
+			detached = ctx.NewClassInstance(cls, nil).Detach()
+			return nil
+		})
+		if finalized != 0 || !kept.IsInstanceOfClass(cls) || !detached.IsInstanceOfClass(cls) {
+			t.Errorf(`[scope check]: expected the kept and detached values to survive the scope, got: "%d" finalizations, for test: "%s"`, finalized, test_name)
+		}
+		kept.Free()
+		detached.Free()
+		if finalized != 2 {
+			t.Errorf(`[scope check]: expected both instances to be finalized once freed, got: "%d" finalizations, for test: "%s"`, finalized, test_name)
+		}
+	})
+}
@@ -0,0 +1,289 @@
+// this file contains tests for `serialize.go` file under the [bridge] package.
+
+package bridge_test
+
+import (
+	errors "errors"
+	math "math"
+	big "math/big"
+	testing "testing"
+
+	js "github.com/oazmi/quiccjs/pkg/bridge"
+)
+
+func TestSerialize_RoundTrip(t *testing.T) {
+	rt := js.NewRuntime()
+	defer rt.Free()
+	ctx := rt.NewContext()
+	defer ctx.Free()
+	// values are deserialized into a different runtime, to ensure that nothing is shared with the original.
+	other_rt := js.NewRuntime()
+	defer other_rt.Free()
+	other_ctx := other_rt.NewContext()
+	defer other_ctx.Free()
+
+	test_bigint, _ := (&big.Int{}).SetString("123456789012345678901234567890", 10)
+	type testCase struct {
+		name     string
+		createFn func() *js.Value
+		checkVal func(*js.Value) bool
+	}
+	// these test cases mirror the value types tested in `value_test.go`.
+	tests := []testCase{{
+		name:     "Null",
+		createFn: ctx.NewNull,
+		checkVal: (*js.Value).IsNull,
+	}, {
+		name:     "Undefined",
+		createFn: ctx.NewUndefined,
+		checkVal: (*js.Value).IsUndefined,
+	}, {
+		name:     "True",
+		createFn: func() *js.Value { return ctx.NewBool(true) },
+		checkVal: func(v *js.Value) bool { return v.IsBool() && v.ToBool() },
+	}, {
+		name:     "False",
+		createFn: func() *js.Value { return ctx.NewBool(false) },
+		checkVal: func(v *js.Value) bool { return v.IsBool() && !v.ToBool() },
+	}, {
+		name:     "Int32",
+		createFn: func() *js.Value { return ctx.NewInt32(-110011) },
+		checkVal: func(v *js.Value) bool { return v.ToInt32() == -110011 },
+	}, {
+		name:     "Uint32",
+		createFn: func() *js.Value { return ctx.NewUint32(4294857285) },
+		checkVal: func(v *js.Value) bool { return v.ToUint32() == 4294857285 },
+	}, {
+		name:     "Int64",
+		createFn: func() *js.Value { return ctx.NewInt64(-9007199254740991) },
+		checkVal: func(v *js.Value) bool { return v.ToInt64() == -9007199254740991 },
+	}, {
+		name:     "Float64",
+		createFn: func() *js.Value { return ctx.NewFloat64(123.456) },
+		checkVal: func(v *js.Value) bool { return v.ToFloat64() == 123.456 },
+	}, {
+		name:     "Float64 - max value",
+		createFn: func() *js.Value { return ctx.NewFloat64(math.MaxFloat64) },
+		checkVal: func(v *js.Value) bool { return v.ToFloat64() == math.MaxFloat64 },
+	}, {
+		name:     "Float64 - infinities",
+		createFn: func() *js.Value { return ctx.NewFloat64(math.Inf(-1)) },
+		checkVal: func(v *js.Value) bool { return math.IsInf(v.ToFloat64(), -1) },
+	}, {
+		name:     "BigInt64",
+		createFn: func() *js.Value { return ctx.NewBigInt64(-18014398509481983) },
+		checkVal: func(v *js.Value) bool { return v.IsBigInt() && v.ToBigInt64() == -18014398509481983 },
+	}, {
+		name:     "BigUint64",
+		createFn: func() *js.Value { return ctx.NewBigUint64(math.MaxUint64) },
+		checkVal: func(v *js.Value) bool { return v.IsBigInt() && v.ToBigInt().Uint64() == math.MaxUint64 },
+	}, {
+		name:     "BigInt - math/big",
+		createFn: func() *js.Value { return ctx.NewBigInt(test_bigint) },
+		checkVal: func(v *js.Value) bool { return v.IsBigInt() && v.ToBigInt().Cmp(test_bigint) == 0 },
+	}, {
+		name:     "String - with null character",
+		createFn: func() *js.Value { return ctx.NewString("hello \x00 world!") },
+		checkVal: func(v *js.Value) bool { return v.IsString() && v.ToString() == "hello \x00 world!" },
+	}, {
+		name:     "Uint8Array",
+		createFn: func() *js.Value { return ctx.NewTypedArrayFromBytes(js.TypedArrayUint8, []byte{1, 2, 3}) },
+		checkVal: func(v *js.Value) bool {
+			return v.IsTypedArray(js.TypedArrayUint8) && string(v.ToByteArray()) == "\x01\x02\x03"
+		},
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			val := tc.createFn()
+			defer val.Free()
+			data, err := val.Serialize(js.SerializeOptions{})
+			if err != nil {
+				t.Fatalf(`[serialize check]: unexpected error: "%s", for test: "%s"`, err.Error(), tc.name)
+			}
+			decoded, err := other_ctx.Deserialize(data)
+			if err != nil {
+				t.Fatalf(`[deserialize check]: unexpected error: "%s", for test: "%s"`, err.Error(), tc.name)
+			}
+			defer decoded.Free()
+			if !tc.checkVal(decoded) {
+				t.Errorf(`[value check]: round trip failed for: "%s"`, tc.name)
+			}
+		})
+	}
+
+	test_name := "Symbol - not serializable"
+	t.Run(test_name, func(t *testing.T) {
+		description := ctx.NewString("some unique symbol!")
+		defer description.Free()
+		sym := ctx.NewSymbol(description)
+		defer sym.Free()
+		if _, err := sym.Serialize(js.SerializeOptions{}); err == nil {
+			t.Errorf(`[error check]: expected symbols to fail serialization, for test: "%s"`, test_name)
+		}
+	})
+
+	test_name = "Map, Set, and cyclic references"
+	t.Run(test_name, func(t *testing.T) {
+		val, err := ctx.Eval(`
+			const set = new Set([1n, "two"]);
+			const graph = { map: new Map([["set", set], [set, new Date(0)]]), set, list: [1, 2, 3] };
+			graph.self = graph;
+			set.add(graph);
+			graph
+		`)
+		if err != nil {
+			t.Fatalf(`[eval check ]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		defer val.Free()
+		data, err := val.Serialize(js.SerializeOptions{References: true})
+		if err != nil {
+			t.Fatalf(`[serialize check]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		decoded, err := other_ctx.Deserialize(data)
+		if err != nil {
+			t.Fatalf(`[deserialize check]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		other_ctx.GetGlobalThis().Set("graph", decoded)
+		summary, err := other_ctx.Eval(`[
+			graph.self === graph,
+			graph.map instanceof Map,
+			graph.map.get("set") === graph.set,
+			graph.set.has(graph),
+			graph.set.has(1n),
+			graph.map.get(graph.set) instanceof Date,
+			graph.list.join("-"),
+		].join(",")`)
+		if err != nil {
+			t.Fatalf(`[eval check ]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		defer summary.Free()
+		expected := "true,true,true,true,true,true,1-2-3"
+		if got := summary.ToString(); got != expected {
+			t.Errorf(`[value check]: expected value: "%s", got: "%s", for test: "%s"`, expected, got, test_name)
+		}
+	})
+
+	test_name = "Map and Set - immune to patched intrinsics"
+	t.Run(test_name, func(t *testing.T) {
+		// every intrinsic that a naive codec would rely on is replaced with a spy, in both the serializing and the deserializing contexts.
+		const patch = `
+			globalThis.tampered = 0;
+			globalThis.genuine = { apply: Reflect.apply, get: Map.prototype.get, has: Set.prototype.has };
+			const spy = function () { tampered++; throw new Error("tampered") };
+			const protos = [Map.prototype, Set.prototype, Array.prototype];
+			const keys = ["set", "get", "has", "add", "forEach", "push", "map", Symbol.iterator];
+			for (let i = 0; i < protos.length; i++) {
+				for (let j = 0; j < keys.length; j++) { protos[i][keys[j]] = spy }
+			}
+			Object.defineProperty(Object.prototype, "0", { set: spy, configurable: true });
+			Object.defineProperty(Map, Symbol.hasInstance, { value: spy });
+			globalThis.Map = globalThis.Set = globalThis.Array = globalThis.Reflect = globalThis.Object = spy;
+		`
+		src_ctx := rt.NewContext()
+		defer src_ctx.Free()
+		dst_ctx := other_rt.NewContext()
+		defer dst_ctx.Free()
+		val, err := src_ctx.Eval(`
+			const set = new Set([1, "two"]);
+			globalThis.data = { map: new Map([["set", set]]), set, list: [set] };
+		` + patch + `data`)
+		if err != nil {
+			t.Fatalf(`[eval check ]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		defer val.Free()
+		data, err := val.Serialize(js.SerializeOptions{})
+		if err != nil {
+			t.Fatalf(`[serialize check]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		patched, err := dst_ctx.Eval(patch)
+		if err != nil {
+			t.Fatalf(`[eval check ]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		patched.Free()
+		decoded, err := dst_ctx.Deserialize(data)
+		if err != nil {
+			t.Fatalf(`[deserialize check]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		dst_ctx.GetGlobalThis().Set("decoded", decoded)
+		summary, err := dst_ctx.Eval(`[
+			tampered,
+			genuine.apply(genuine.get, decoded.map, ["set"]) === decoded.set,
+			genuine.apply(genuine.has, decoded.set, ["two"]),
+			decoded.list[0] === decoded.set,
+		].join(",")`)
+		if err != nil {
+			t.Fatalf(`[eval check ]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		defer summary.Free()
+		expected := "0,true,true,true"
+		if got := summary.ToString(); got != expected {
+			t.Errorf(`[value check]: expected value: "%s", got: "%s", for test: "%s"`, expected, got, test_name)
+		}
+		src_tampered, err := src_ctx.Eval(`tampered`)
+		if err != nil {
+			t.Fatalf(`[eval check ]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		defer src_tampered.Free()
+		if got := src_tampered.ToInt32(); got != 0 {
+			t.Errorf(`[value check]: expected the serializing context's spies to stay untouched, got: "%d" calls, for test: "%s"`, got, test_name)
+		}
+	})
+
+	test_name = "Deserialize - rejects foreign data"
+	t.Run(test_name, func(t *testing.T) {
+		if _, err := other_ctx.Deserialize([]byte("definitely not serialized")); !errors.Is(err, js.ErrBytecodeVersion) {
+			t.Errorf(`[error check]: expected an "ErrBytecodeVersion" error, got: "%v", for test: "%s"`, err, test_name)
+		}
+	})
+
+	test_name = "SerializeShared - shares memory only when permitted"
+	t.Run(test_name, func(t *testing.T) {
+		buf := js.NewSharedBuffer(4)
+		defer buf.Release()
+		sab := ctx.NewSharedArrayBuffer(buf)
+		defer sab.Free()
+		if _, err := sab.Serialize(js.SerializeOptions{}); err == nil {
+			t.Errorf(`[error check]: expected "Serialize" to reject shared array buffers, for test: "%s"`, test_name)
+		}
+		data, refs, err := sab.SerializeShared(js.SerializeOptions{})
+		if err != nil {
+			t.Fatalf(`[serialize check]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		defer refs.Release()
+		if _, err := other_ctx.Deserialize(data); !errors.Is(err, js.ErrSharedArrayBuffers) {
+			t.Errorf(`[error check]: expected an "ErrSharedArrayBuffers" error, got: "%v", for test: "%s"`, err, test_name)
+		}
+		decoded, err := other_ctx.DeserializeWithOptions(data, js.DeserializeOptions{SharedArrayBuffers: true})
+		if err != nil {
+			t.Fatalf(`[deserialize check]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		defer decoded.Free()
+		other_ctx.GetGlobalThis().Set("shared", decoded.Dupe())
+		written, err := other_ctx.Eval(`new Uint8Array(shared)[0] = 42`)
+		if err != nil {
+			t.Fatalf(`[eval check ]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		written.Free()
+		if got := buf.Bytes()[0]; got != 42 {
+			t.Errorf(`[value check]: expected the write to be visible through the shared memory, got: "%d", for test: "%s"`, got, test_name)
+		}
+	})
+
+	test_name = "Deserialize - rejects unknown shared memory blocks"
+	t.Run(test_name, func(t *testing.T) {
+		buf := js.NewSharedBuffer(4)
+		sab := ctx.NewSharedArrayBuffer(buf)
+		data, refs, err := sab.SerializeShared(js.SerializeOptions{})
+		if err != nil {
+			t.Fatalf(`[serialize check]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		// once every reference is gone, the block is released, and the data embeds a dangling address.
+		refs.Release()
+		sab.Free()
+		buf.Release()
+		if _, err := other_ctx.DeserializeWithOptions(data, js.DeserializeOptions{SharedArrayBuffers: true}); !errors.Is(err, js.ErrSharedArrayBuffers) {
+			t.Errorf(`[error check]: expected an "ErrSharedArrayBuffers" error, got: "%v", for test: "%s"`, err, test_name)
+		}
+	})
+}
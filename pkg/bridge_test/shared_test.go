@@ -0,0 +1,95 @@
+// this file contains tests for `shared.go` file under the [bridge] package.
+
+package bridge_test
+
+import (
+	context "context"
+	testing "testing"
+	time "time"
+
+	js "github.com/oazmi/quiccjs/pkg/bridge"
+)
+
+func TestSharedBuffer(t *testing.T) {
+	rt := js.NewRuntime()
+	defer rt.Free()
+	ctx := rt.NewContext()
+	defer ctx.Free()
+
+	test_name := "NewSharedArrayBuffer - writes are visible both ways"
+	t.Run(test_name, func(t *testing.T) {
+		buf := js.NewSharedBuffer(8)
+		defer buf.Release()
+		buf.Bytes()[0] = 7
+		sab := ctx.NewSharedArrayBuffer(buf)
+		if !sab.IsSharedArrayBuffer() {
+			t.Fatalf(`[type check]: expected a "SharedArrayBuffer", for test: "%s"`, test_name)
+		}
+		ctx.GetGlobalThis().Set("sab", sab)
+		first, err := evalInt(ctx, `const bytes = new Uint8Array(sab); bytes[1] = 42; bytes[0]`)
+		if err != nil || first != 7 {
+			t.Errorf(`[read check]: expected "7", got: "%d" (error: "%v"), for test: "%s"`, first, err, test_name)
+		}
+		if second := buf.Bytes()[1]; second != 42 {
+			t.Errorf(`[write check]: expected "42", got: "%d", for test: "%s"`, second, test_name)
+		}
+	})
+
+	test_name = "ToSharedBuffer - outlives the javascript buffer"
+	t.Run(test_name, func(t *testing.T) {
+		view, err := ctx.Eval(`globalThis.view = new Int32Array(new SharedArrayBuffer(16), 4); view[0] = 99; view`)
+		if err != nil {
+			t.Fatalf(`[eval check]: unexpected error: "%v", for test: "%s"`, err, test_name)
+		}
+		buf := view.ToSharedBuffer()
+		view.Free()
+		if buf == nil || buf.Len() != 16 {
+			t.Fatalf(`[buffer check]: expected a "16" byte buffer, got: "%v", for test: "%s"`, buf, test_name)
+		}
+		defer buf.Release()
+		result, _ := ctx.Eval(`delete globalThis.view`)
+		result.Free()
+		rt.RunGC()
+		if value := *buf.Int32(1); value != 99 {
+			t.Errorf(`[value check]: expected "99", got: "%d", for test: "%s"`, value, test_name)
+		}
+		plain, _ := ctx.Eval(`new ArrayBuffer(8)`)
+		defer plain.Free()
+		if plain.ToSharedBuffer() != nil {
+			t.Errorf(`[plain check]: expected "nil" for a non-shared buffer, for test: "%s"`, test_name)
+		}
+	})
+
+	test_name = "postMessage - shared with a worker instead of copied"
+	t.Run(test_name, func(t *testing.T) {
+		err := ctx.RegisterWorkers(js.WorkerOptions{Loader: mapModuleLoader{
+			"fill.js": `onmessage = (event) => { new Int32Array(event.data).fill(42); postMessage("filled"); close() }`,
+		}})
+		if err != nil {
+			t.Fatalf(`[register check]: unexpected error: "%v", for test: "%s"`, err, test_name)
+		}
+		spawned, err := ctx.Eval(`
+			const shared = new SharedArrayBuffer(8)
+			const filler = new Worker("./fill.js")
+			filler.onmessage = (event) => { globalThis.fill_state = event.data }
+			filler.postMessage(shared)
+		`)
+		if err != nil {
+			t.Fatalf(`[eval check]: unexpected error: "%v", for test: "%s"`, err, test_name)
+		}
+		spawned.Free()
+		goctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := rt.RunLoop(goctx); err != nil {
+			t.Fatalf(`[loop check]: unexpected error: "%v", for test: "%s"`, err, test_name)
+		}
+		result, err := ctx.Eval(`fill_state + ": " + new Int32Array(shared).join(",")`)
+		if err != nil {
+			t.Fatalf(`[eval check]: unexpected error: "%v", for test: "%s"`, err, test_name)
+		}
+		defer result.Free()
+		if str := result.ToString(); str != "filled: 42,42" {
+			t.Errorf(`[shared check]: expected "filled: 42,42", got: "%s", for test: "%s"`, str, test_name)
+		}
+	})
+}
@@ -0,0 +1,144 @@
+// this file contains tests for `timers.go` file under the [bridge] package.
+
+package bridge_test
+
+import (
+	context "context"
+	strings "strings"
+	testing "testing"
+	time "time"
+
+	js "github.com/oazmi/quiccjs/pkg/bridge"
+)
+
+func TestTimers_FakeClock(t *testing.T) {
+	rt := js.NewRuntime()
+	defer rt.Free()
+	ctx := rt.NewContext()
+	defer ctx.Free()
+	clock := js.NewFakeClock(time.Unix(0, 0))
+	rt.SetClock(clock)
+	ctx.RegisterTimers()
+
+	log_of := func(t *testing.T, test_name string) string {
+		log, err := ctx.Eval(`log.join(",")`)
+		if err != nil {
+			t.Fatalf(`[eval check ]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		defer log.Free()
+		return log.ToString()
+	}
+
+	test_name := "setTimeout - ordering"
+	t.Run(test_name, func(t *testing.T) {
+		_, err := ctx.Eval(`
+			globalThis.log = [];
+			setTimeout(() => log.push("c"), 200);
+			setTimeout((a, b) => log.push(a + b), 100, "a", "1");
+			setTimeout(() => log.push("b"), 100);
+			const cancelled = setTimeout(() => log.push("never"), 50);
+			clearTimeout(cancelled);
+			queueMicrotask(() => log.push("micro"));
+		`)
+		if err != nil {
+			t.Fatalf(`[eval check ]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		if err := rt.RunReady(); err != nil {
+			t.Fatalf(`[loop check ]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		if got := log_of(t, test_name); got != "micro" {
+			t.Errorf(`[value check]: expected log: "%s", got: "%s", for test: "%s"`, "micro", got, test_name)
+		}
+		clock.Advance(100 * time.Millisecond)
+		rt.RunReady()
+		if got := log_of(t, test_name); got != "micro,a1,b" {
+			t.Errorf(`[value check]: expected log: "%s", got: "%s", for test: "%s"`, "micro,a1,b", got, test_name)
+		}
+		clock.Advance(100 * time.Millisecond)
+		rt.RunReady()
+		if got := log_of(t, test_name); got != "micro,a1,b,c" {
+			t.Errorf(`[value check]: expected log: "%s", got: "%s", for test: "%s"`, "micro,a1,b,c", got, test_name)
+		}
+	})
+
+	test_name = "setInterval - cleared from within"
+	t.Run(test_name, func(t *testing.T) {
+		_, err := ctx.Eval(`
+			globalThis.log = [];
+			const id = setInterval(() => { log.push(log.length); if (log.length === 3) { clearInterval(id) } }, 10);
+		`)
+		if err != nil {
+			t.Fatalf(`[eval check ]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		for range 5 {
+			clock.Advance(10 * time.Millisecond)
+			rt.RunReady()
+		}
+		if got := log_of(t, test_name); got != "0,1,2" {
+			t.Errorf(`[value check]: expected log: "%s", got: "%s", for test: "%s"`, "0,1,2", got, test_name)
+		}
+		// no timers remain, so the loop must exit immediately.
+		if err := rt.RunLoop(context.Background()); err != nil {
+			t.Errorf(`[loop check ]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+	})
+
+	test_name = "clearTimeout - cannot clear the timers of another context"
+	t.Run(test_name, func(t *testing.T) {
+		other_ctx := rt.NewContext()
+		defer other_ctx.Free()
+		other_ctx.RegisterTimers()
+		id, err := ctx.Eval(`globalThis.log = []; setTimeout(() => log.push("kept"), 10)`)
+		if err != nil {
+			t.Fatalf(`[eval check ]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		other_ctx.GetGlobalThis().Set("foreign_id", id)
+		cleared, err := other_ctx.Eval(`clearTimeout(foreign_id); clearInterval(foreign_id)`)
+		if err != nil {
+			t.Fatalf(`[eval check ]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		cleared.Free()
+		clock.Advance(10 * time.Millisecond)
+		rt.RunReady()
+		if got := log_of(t, test_name); got != "kept" {
+			t.Errorf(`[value check]: expected log: "%s", got: "%s", for test: "%s"`, "kept", got, test_name)
+		}
+	})
+
+	test_name = "setTimeout - thrown callback"
+	t.Run(test_name, func(t *testing.T) {
+		_, err := ctx.Eval(`setTimeout(() => { throw new Error("timer failure") }, 0)`)
+		if err != nil {
+			t.Fatalf(`[eval check ]: unexpected error: "%s", for test: "%s"`, err.Error(), test_name)
+		}
+		if err := rt.RunReady(); err == nil || !strings.Contains(err.Error(), "timer failure") {
+			t.Errorf(`[loop check ]: expected the thrown error, got: "%v", for test: "%s"`, err, test_name)
+		}
+	})
+}
+
+func TestTimers_SystemClock(t *testing.T) {
+	rt := js.NewRuntime()
+	defer rt.Free()
+	ctx := rt.NewContext()
+	defer ctx.Free()
+	ctx.RegisterTimers()
+
+	promise, err := ctx.Eval(`new Promise((resolve) => setTimeout(resolve, 20, "waited"))`)
+	if err != nil {
+		t.Fatalf(`[eval check ]: unexpected error: "%s"`, err.Error())
+	}
+	defer promise.Free()
+	start := time.Now()
+	result, err := promise.Await(context.Background())
+	if err != nil {
+		t.Fatalf(`[await check]: unexpected error: "%s"`, err.Error())
+	}
+	defer result.Free()
+	if got := result.ToString(); got != "waited" {
+		t.Errorf(`[value check]: expected value: "%s", got: "%s"`, "waited", got)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf(`[time check ]: expected to wait at least 20ms, but only waited: %s`, elapsed)
+	}
+}
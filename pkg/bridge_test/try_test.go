@@ -0,0 +1,88 @@
+// this file contains tests for `try.go` file under the [bridge] package.
+
+package bridge_test
+
+import (
+	errors "errors"
+	testing "testing"
+
+	js "github.com/oazmi/quiccjs/pkg/bridge"
+)
+
+func TestTry(t *testing.T) {
+	rt := js.NewRuntime()
+	defer rt.Free()
+	ctx := rt.NewContext()
+	defer ctx.Free()
+
+	obj, err := ctx.Eval(`Object.defineProperties({}, {
+		fixed: { value: 1, writable: false, configurable: false },
+		broken: { get() { throw new RangeError("getter failed") } },
+	})`)
+	if err != nil {
+		t.Fatalf(`[eval check]: unexpected error: "%v"`, err)
+	}
+	defer obj.Free()
+	num := ctx.NewInt32(7)
+	defer num.Free()
+
+	expect_error := func(t *testing.T, test_name string, err error, name string) {
+		var js_err *js.Error
+		if !errors.As(err, &js_err) || js_err.Name != name {
+			t.Errorf(`[error check]: expected a "%s", got: "%v", for test: "%s"`, name, err, test_name)
+		}
+		if err := ctx.GetException(); err != nil {
+			t.Errorf(`[exception check]: expected the exception to be cleared, got: "%v", for test: "%s"`, err, test_name)
+		}
+	}
+
+	test_name := "TryCall - not a function"
+	t.Run(test_name, func(t *testing.T) {
+		_, err := num.TryCall(nil)
+		expect_error(t, test_name, err, "TypeError")
+	})
+
+	test_name = "TryCall - success"
+	t.Run(test_name, func(t *testing.T) {
+		fn, _ := ctx.Eval(`(a, b) => a + b`)
+		defer fn.Free()
+		result, err := fn.TryCall(nil, ctx.NewInt32(1), ctx.NewInt32(2))
+		if err != nil || result.ToInt32() != 3 {
+			t.Fatalf(`[call check]: expected "3", got error: "%v", for test: "%s"`, err, test_name)
+		}
+		result.Free()
+	})
+
+	test_name = "TryConstruct - not a constructor"
+	t.Run(test_name, func(t *testing.T) {
+		_, err := num.TryConstruct()
+		expect_error(t, test_name, err, "TypeError")
+	})
+
+	test_name = "TryGet - throwing getter"
+	t.Run(test_name, func(t *testing.T) {
+		_, err := obj.TryGet("broken")
+		expect_error(t, test_name, err, "RangeError")
+	})
+
+	test_name = "TrySet - read-only property"
+	t.Run(test_name, func(t *testing.T) {
+		err := obj.TrySet("fixed", ctx.NewInt32(2))
+		expect_error(t, test_name, err, "TypeError")
+	})
+
+	test_name = "TryHas - not an object"
+	t.Run(test_name, func(t *testing.T) {
+		_, err := num.TryHas("x")
+		expect_error(t, test_name, err, "TypeError")
+		if has, err := obj.TryHas("fixed"); err != nil || !has {
+			t.Errorf(`[has check]: expected "true", got: "%v" (error: "%v"), for test: "%s"`, has, err, test_name)
+		}
+	})
+
+	test_name = "TryDelete - non-configurable property"
+	t.Run(test_name, func(t *testing.T) {
+		_, err := obj.TryDelete("fixed")
+		expect_error(t, test_name, err, "TypeError")
+	})
+}
@@ -0,0 +1,103 @@
+// this file contains tests for `worker.go` file under the [bridge] package.
+
+package bridge_test
+
+import (
+	context "context"
+	testing "testing"
+	time "time"
+
+	js "github.com/oazmi/quiccjs/pkg/bridge"
+)
+
+func TestWorker(t *testing.T) {
+	rt := js.NewRuntime()
+	defer rt.Free()
+	ctx := rt.NewContext()
+	defer ctx.Free()
+	ctx.RegisterTimers()
+	err := ctx.RegisterWorkers(js.WorkerOptions{Loader: mapModuleLoader{
+		"echo.js": `onmessage = (event) => {
+			const { n, bytes } = event.data
+			postMessage({ double: n * 2, sum: new Uint8Array(bytes).reduce((a, b) => a + b, 0), map: new Map([["n", n]]) })
+			close()
+		}`,
+		"spin.js":   `postMessage("started"); while (true) {}`,
+		"throw.js":  `throw new Error("oops")`,
+		"module.js": `import { triple } from "./triple.js"; postMessage(triple(14))`,
+		"triple.js": `export const triple = (x) => x * 3`,
+	}})
+	if err != nil {
+		t.Fatalf(`[register check]: unexpected error: "%v"`, err)
+	}
+
+	// evaluate `code`, run the event loop until it runs out of work, and then evaluate `result_code`.
+	run := func(t *testing.T, test_name string, code string, result_code string) string {
+		spawned, err := ctx.Eval(code)
+		if err != nil {
+			t.Fatalf(`[eval check]: unexpected error: "%v", for test: "%s"`, err, test_name)
+		}
+		spawned.Free()
+		goctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := rt.RunLoop(goctx); err != nil {
+			t.Fatalf(`[loop check]: unexpected error: "%v", for test: "%s"`, err, test_name)
+		}
+		result, err := ctx.Eval(result_code)
+		if err != nil {
+			t.Fatalf(`[eval check]: unexpected error: "%v", for test: "%s"`, err, test_name)
+		}
+		defer result.Free()
+		return result.ToString()
+	}
+
+	test_name := "postMessage - round trip with a transferred buffer"
+	t.Run(test_name, func(t *testing.T) {
+		result := run(t, test_name, `
+			const echo = new Worker("./echo.js")
+			echo.onmessage = (event) => { globalThis.reply = event.data }
+			const bytes = new Uint8Array([1, 2, 3, 4]).buffer
+			echo.postMessage({ n: 21, bytes }, [bytes])
+			globalThis.detached_length = bytes.byteLength
+		`, `JSON.stringify([reply.double, reply.sum, reply.map.get("n"), detached_length])`)
+		if result != "[42,10,21,0]" {
+			t.Errorf(`[reply check]: expected "[42,10,21,0]", got: "%s", for test: "%s"`, result, test_name)
+		}
+	})
+
+	test_name = "terminate - interrupts a busy worker"
+	t.Run(test_name, func(t *testing.T) {
+		result := run(t, test_name, `
+			const spin = new Worker("./spin.js")
+			spin.onmessage = (event) => {
+				globalThis.spin_state = event.data
+				setTimeout(() => { spin.terminate(); globalThis.spin_state = "terminated" }, 10)
+			}
+		`, `spin_state`)
+		if result != "terminated" {
+			t.Errorf(`[state check]: expected "terminated", got: "%s", for test: "%s"`, result, test_name)
+		}
+	})
+
+	test_name = "onerror - uncaught error inside of the worker"
+	t.Run(test_name, func(t *testing.T) {
+		result := run(t, test_name, `
+			const thrower = new Worker("./throw.js")
+			thrower.onerror = (event) => { globalThis.worker_error = event.message }
+		`, `worker_error`)
+		if result != "[Error]: oops" {
+			t.Errorf(`[error check]: expected "[Error]: oops", got: "%s", for test: "%s"`, result, test_name)
+		}
+	})
+
+	test_name = "Worker - es-module script with an import"
+	t.Run(test_name, func(t *testing.T) {
+		result := run(t, test_name, `
+			const mod = new Worker("./module.js", { type: "module" })
+			mod.onmessage = (event) => { globalThis.module_reply = event.data }
+		`, `String(module_reply)`)
+		if result != "42" {
+			t.Errorf(`[reply check]: expected "42", got: "%s", for test: "%s"`, result, test_name)
+		}
+	})
+}
@@ -0,0 +1,27 @@
+// package bridgetest contains testing helpers for code that is built on top of the [bridge] package.
+package bridgetest
+
+import (
+	testing "testing"
+
+	bridge "github.com/oazmi/quiccjs/pkg/bridge"
+)
+
+// report the leaked (and double-freed) values and atoms of the context `ctx` as test errors, once the context is freed.
+// call it right after creating the context, and make sure that the context gets freed before the test ends:
+//
+//	ctx := rt.NewContext()
+//	bridgetest.CheckLeaks(t, ctx)
+//	defer ctx.Free()
+//
+// leaks are only detected when the tests are built with the `quiccjs_debug` tag (see [bridge.LeakDetection]),
+// for instance: `go test -tags="quiccjs_debug" ./...`. otherwise, this function does nothing.
+func CheckLeaks(t testing.TB, ctx *bridge.Context) {
+	t.Helper()
+	ctx.OnLeak(func(leaks []bridge.Leak) {
+		t.Helper()
+		for _, leak := range leaks {
+			t.Errorf("[leak check]: %s", leak)
+		}
+	})
+}